@@ -23,4 +23,9 @@ resource "upcloud_server" "example" {
   network_interface {
     ip_address_family = "IPv6"
   }
+
+  storage_devices {
+    size = 10
+    tier = "maxiops"
+  }
 }
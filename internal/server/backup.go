@@ -0,0 +1,28 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const simpleBackupDisabled = "no"
+
+// buildSimpleBackupForServer formats the block as the API's `<time>,<plan>` value.
+func buildSimpleBackupForServer(backup []simpleBackupModel) string {
+	if len(backup) == 0 {
+		return simpleBackupDisabled
+	}
+	return backup[0].Time.ValueString() + "," + backup[0].Plan.ValueString()
+}
+
+func simpleBackupFromServerDetails(value string) []simpleBackupModel {
+	time, plan, ok := strings.Cut(value, ",")
+	if !ok {
+		return make([]simpleBackupModel, 0)
+	}
+	return []simpleBackupModel{{
+		Plan: types.StringValue(plan),
+		Time: types.StringValue(time),
+	}}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func firewallAddressAttribute(description string) schema.StringAttribute {
+	return schema.StringAttribute{
+		MarkdownDescription: description,
+		Optional:            true,
+		Validators: []validator.String{
+			stringvalidator.LengthAtLeast(1),
+		},
+	}
+}
+
+func firewallPortAttribute(description string) schema.StringAttribute {
+	return schema.StringAttribute{
+		MarkdownDescription: description,
+		Optional:            true,
+		Validators: []validator.String{
+			stringvalidator.RegexMatches(firewallPortRegexp, "must be a port number between 1 and 65535"),
+		},
+	}
+}
+
+func firewallEnabled(enabled types.Bool) string {
+	if enabled.ValueBool() {
+		return "on"
+	}
+	return "off"
+}
+
+func buildFirewallRulesForServer(rules []firewallRuleModel) request.FirewallRuleSlice {
+	response := make(request.FirewallRuleSlice, 0, len(rules))
+	for _, rule := range rules {
+		response = append(response, upcloud.FirewallRule{
+			Direction:               rule.Direction.ValueString(),
+			Action:                  rule.Action.ValueString(),
+			Family:                  rule.Family.ValueString(),
+			Protocol:                rule.Protocol.ValueString(),
+			SourceAddressStart:      rule.SourceAddressStart.ValueString(),
+			SourceAddressEnd:        rule.SourceAddressEnd.ValueString(),
+			SourcePortStart:         rule.SourcePortStart.ValueString(),
+			SourcePortEnd:           rule.SourcePortEnd.ValueString(),
+			DestinationAddressStart: rule.DestinationAddressStart.ValueString(),
+			DestinationAddressEnd:   rule.DestinationAddressEnd.ValueString(),
+			DestinationPortStart:    rule.DestinationPortStart.ValueString(),
+			DestinationPortEnd:      rule.DestinationPortEnd.ValueString(),
+			Comment:                 rule.Comment.ValueString(),
+		})
+	}
+	return response
+}
+
+func firewallRulesEquals(a, b []firewallRuleModel) bool {
+	return reflect.DeepEqual(buildFirewallRulesForServer(a), buildFirewallRulesForServer(b))
+}
+
+// replaceServerFirewallRules replaces the whole rule set of the server in a single request.
+func replaceServerFirewallRules(ctx context.Context, svc *service.Service, uuid string, rules []firewallRuleModel) error {
+	if err := svc.CreateFirewallRules(ctx, &request.CreateFirewallRulesRequest{
+		ServerUUID:    uuid,
+		FirewallRules: buildFirewallRulesForServer(rules),
+	}); err != nil {
+		return fmt.Errorf("unable to replace firewall rules; %w", err)
+	}
+	return nil
+}
+
+func readServerFirewallRules(ctx context.Context, svc *service.Service, data *serverModel) error {
+	rules, err := svc.GetFirewallRules(ctx, &request.GetFirewallRulesRequest{
+		ServerUUID: data.ID.ValueString(),
+	})
+	if err != nil {
+		return err
+	}
+
+	data.FirewallRule = make([]firewallRuleModel, 0, len(rules.FirewallRules))
+	for _, rule := range rules.FirewallRules {
+		data.FirewallRule = append(data.FirewallRule, firewallRuleModel{
+			Direction:               types.StringValue(rule.Direction),
+			Action:                  types.StringValue(rule.Action),
+			Family:                  stringValueOrNull(rule.Family),
+			Protocol:                stringValueOrNull(rule.Protocol),
+			SourceAddressStart:      stringValueOrNull(rule.SourceAddressStart),
+			SourceAddressEnd:        stringValueOrNull(rule.SourceAddressEnd),
+			SourcePortStart:         stringValueOrNull(rule.SourcePortStart),
+			SourcePortEnd:           stringValueOrNull(rule.SourcePortEnd),
+			DestinationAddressStart: stringValueOrNull(rule.DestinationAddressStart),
+			DestinationAddressEnd:   stringValueOrNull(rule.DestinationAddressEnd),
+			DestinationPortStart:    stringValueOrNull(rule.DestinationPortStart),
+			DestinationPortEnd:      stringValueOrNull(rule.DestinationPortEnd),
+			Comment:                 stringValueOrNull(rule.Comment),
+		})
+	}
+
+	return nil
+}
+
+func stringValueOrNull(value string) types.String {
+	if value == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(value)
+}
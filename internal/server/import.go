@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+)
+
+const (
+	importTitlePrefix = "title:"
+
+	// private state key marking servers which were imported instead of created by this resource
+	importedPrivateKey = "imported"
+)
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// resolveImportID returns the UUID of the server identified by the import ID, which is either
+// an UUID, a hostname, `title:<title>` or `<zone>/<hostname>`.
+func resolveImportID(ctx context.Context, svc *service.Service, id string) (string, error) {
+	if uuidRegexp.MatchString(id) {
+		return id, nil
+	}
+
+	servers, err := svc.GetServers(ctx)
+	if err != nil {
+		return "", err
+	}
+	return findServerByImportID(servers.Servers, id)
+}
+
+func findServerByImportID(servers []upcloud.Server, id string) (string, error) {
+	var match func(upcloud.Server) bool
+	switch {
+	case strings.HasPrefix(id, importTitlePrefix):
+		title := strings.TrimPrefix(id, importTitlePrefix)
+		match = func(s upcloud.Server) bool { return s.Title == title }
+	case strings.Contains(id, "/"):
+		zone, hostname, _ := strings.Cut(id, "/")
+		match = func(s upcloud.Server) bool { return s.Zone == zone && s.Hostname == hostname }
+	default:
+		match = func(s upcloud.Server) bool { return s.Hostname == id }
+	}
+
+	matches := make([]upcloud.Server, 0)
+	for _, s := range servers {
+		if match(s) {
+			matches = append(matches, s)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no server matches %q; expected an UUID, a hostname, `title:<title>` or `<zone>/<hostname>`", id)
+	case 1:
+		return matches[0].UUID, nil
+	}
+
+	candidates := make([]string, 0, len(matches))
+	for _, s := range matches {
+		candidates = append(candidates, fmt.Sprintf("%s (%s/%s)", s.UUID, s.Zone, s.Hostname))
+	}
+	return "", fmt.Errorf("%d servers match %q, import one of them by UUID or `<zone>/<hostname>`: %s", len(matches), id, strings.Join(candidates, ", "))
+}
+
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+func wasImported(ctx context.Context, private privateState) (bool, diag.Diagnostics) {
+	if private == nil {
+		return false, nil
+	}
+	value, diags := private.GetKey(ctx, importedPrivateKey)
+	return len(value) > 0, diags
+}
+
+// setConfigOnlyDefaults sets the attributes which can not be read from the API to their defaults,
+// so that the first plan after an import or a state upgrade is clean.
+func setConfigOnlyDefaults(ctx context.Context, state *tfsdk.State) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags.Append(state.SetAttribute(ctx, path.Root("stop_type"), upcloud.StopTypeSoft)...)
+	diags.Append(state.SetAttribute(ctx, path.Root("shutdown_timeout"), defaultShutdownTimeout)...)
+	diags.Append(state.SetAttribute(ctx, path.Root("allow_restart"), true)...)
+	diags.Append(state.SetAttribute(ctx, path.Root("delete_storages"), true)...)
+	diags.Append(state.SetAttribute(ctx, path.Root("rollback_on_failure"), false)...)
+	return diags
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindServerByImportID(t *testing.T) {
+	servers := []upcloud.Server{
+		{UUID: "a", Hostname: "web", Title: "web server", Zone: "fi-hel1"},
+		{UUID: "b", Hostname: "web", Title: "web server", Zone: "de-fra1"},
+		{UUID: "c", Hostname: "db", Title: "database", Zone: "fi-hel1"},
+	}
+
+	testCases := []struct {
+		name string
+		id   string
+		uuid string
+		err  string
+	}{
+		{
+			name: "hostname",
+			id:   "db",
+			uuid: "c",
+		},
+		{
+			name: "zone and hostname",
+			id:   "de-fra1/web",
+			uuid: "b",
+		},
+		{
+			name: "title",
+			id:   "title:database",
+			uuid: "c",
+		},
+		{
+			name: "ambiguous hostname",
+			id:   "web",
+			err:  "2 servers match \"web\"",
+		},
+		{
+			name: "ambiguous title",
+			id:   "title:web server",
+			err:  "a (fi-hel1/web), b (de-fra1/web)",
+		},
+		{
+			name: "no match",
+			id:   "fi-hel1/cache",
+			err:  "no server matches \"fi-hel1/cache\"",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			uuid, err := findServerByImportID(servers, testCase.id)
+			if testCase.err != "" {
+				assert.ErrorContains(t, err, testCase.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.uuid, uuid)
+		})
+	}
+}
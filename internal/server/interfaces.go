@@ -4,35 +4,62 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func buildNetworkInterfaceRequestFromServerModel(data *serverModel) ([]request.CreateNetworkInterfaceRequest, error) {
-	if data == nil {
-		return nil, errors.New("serverModel is nil")
-	}
+type networkInterfaceChanges struct {
+	// indexes of current interfaces which have to be deleted
+	delete []int
+	// kept interfaces whose addresses or settings change
+	modify []request.ModifyNetworkInterfaceRequest
+	// interfaces which have to be created
+	create []request.CreateNetworkInterfaceRequest
+}
 
-	response := make([]request.CreateNetworkInterfaceRequest, 0)
-	for i, inter := range data.NetworkInterface {
-		response = append(response, request.CreateNetworkInterfaceRequest{
-			ServerUUID: data.ID.ValueString(),
-			Index:      i + 1,
-			IPAddresses: request.CreateNetworkInterfaceIPAddressSlice{
-				{
-					Family:  inter.IpAddressFamily.ValueString(),
-					Address: inter.IpAddress.ValueString(),
-				},
-			},
-			Type:              inter.Type.ValueString(),
-			Bootable:          upcloud.FromBool(inter.Bootable.ValueBool()),
-			SourceIPFiltering: upcloud.FromBool(inter.SourceIpFiltering.ValueBool()),
+func (c networkInterfaceChanges) isEmpty() bool {
+	return len(c.delete) == 0 && len(c.modify) == 0 && len(c.create) == 0
+}
+
+// buildNetworkInterfaceRequest builds the request for creating the interface; without a known
+// index UpCloud assigns the next free one.
+func buildNetworkInterfaceRequest(serverUUID string, inter networkInterfaceModel) request.CreateNetworkInterfaceRequest {
+	var address, network string
+	if inter.Type.ValueString() == upcloud.NetworkTypePrivate {
+		address = knownStringValue(inter.IpAddress)
+		network = inter.Network.ValueString()
+	}
+	ipAddresses := request.CreateNetworkInterfaceIPAddressSlice{
+		{
+			Family:  inter.IpAddressFamily.ValueString(),
+			Address: address,
+		},
+	}
+	for _, ip := range inter.AdditionalIPAddress {
+		ipAddresses = append(ipAddresses, request.CreateNetworkInterfaceIPAddress{
+			Family:  ip.IpAddressFamily.ValueString(),
+			Address: knownStringValue(ip.IpAddress),
 		})
 	}
-
-	return response, nil
+	var index int
+	if !inter.Index.IsUnknown() {
+		index = int(inter.Index.ValueInt64())
+	}
+	return request.CreateNetworkInterfaceRequest{
+		ServerUUID:        serverUUID,
+		Index:             index,
+		IPAddresses:       ipAddresses,
+		Type:              inter.Type.ValueString(),
+		NetworkUUID:       network,
+		Bootable:          upcloud.FromBool(inter.Bootable.ValueBool()),
+		SourceIPFiltering: upcloud.FromBool(inter.SourceIpFiltering.ValueBool()),
+	}
 }
 
 func interfacesEquals(a upcloud.ServerInterface, b request.CreateNetworkInterfaceRequest) bool {
@@ -42,16 +69,148 @@ func interfacesEquals(a upcloud.ServerInterface, b request.CreateNetworkInterfac
 	if a.Index != b.Index {
 		return false
 	}
-	if len(a.IPAddresses) != 1 || len(b.IPAddresses) != 1 {
+	if b.NetworkUUID != "" && a.Network != b.NetworkUUID {
 		return false
 	}
-	if a.IPAddresses[0].Family != b.IPAddresses[0].Family {
+	current := a.IPAddresses
+	// only private interfaces have additional addresses, others might have floating IPs attached
+	if a.Type != upcloud.NetworkTypePrivate && len(current) > 1 {
+		current = current[:1]
+	}
+	if len(current) == 0 || len(current) != len(b.IPAddresses) {
 		return false
 	}
+	for i, ip := range b.IPAddresses {
+		if current[i].Family != ip.Family {
+			return false
+		}
+		if ip.Address != "" && current[i].Address != ip.Address {
+			return false
+		}
+	}
 	return true
 }
 
-func reconfigureServerNetworkInterfaces(ctx context.Context, svc *service.Service, data serverModel, reqs []request.CreateNetworkInterfaceRequest) error {
+// interfaceType returns the type of the interface, applying the schema default to unset values.
+func interfaceType(iface networkInterfaceModel) string {
+	if iface.Type.IsNull() {
+		return upcloud.NetworkTypePublic
+	}
+	return iface.Type.ValueString()
+}
+
+// interfaceFamily returns the primary address family of the interface, applying the schema default to unset values.
+func interfaceFamily(iface networkInterfaceModel) string {
+	if iface.IpAddressFamily.IsNull() {
+		return upcloud.IPAddressFamilyIPv4
+	}
+	return iface.IpAddressFamily.ValueString()
+}
+
+// sameNetworkInterface reports whether the planned interface is the current one: type, primary
+// address family and, for private interfaces, network have to match.
+func sameNetworkInterface(current, planned networkInterfaceModel) bool {
+	if planned.Type.IsUnknown() || planned.IpAddressFamily.IsUnknown() {
+		return false
+	}
+	if interfaceType(current) != interfaceType(planned) || interfaceFamily(current) != interfaceFamily(planned) {
+		return false
+	}
+	if interfaceType(planned) != upcloud.NetworkTypePrivate {
+		return true
+	}
+	return !planned.Network.IsUnknown() && current.Network.ValueString() == planned.Network.ValueString()
+}
+
+// matchNetworkInterfaces returns for each planned interface the position of the current interface
+// it keeps, or -1 when it has to be created. Interfaces with an index are matched by it, the rest
+// by identity in order; an unknown index marks an interface created in this plan.
+func matchNetworkInterfaces(current, planned []networkInterfaceModel) []int {
+	matches := make([]int, len(planned))
+	claimed := make([]bool, len(current))
+	reserved := make(map[int64]bool)
+
+	for i, p := range planned {
+		matches[i] = -1
+		if p.Index.IsNull() || p.Index.IsUnknown() {
+			continue
+		}
+		reserved[p.Index.ValueInt64()] = true
+		for j, c := range current {
+			if !claimed[j] && c.Index.Equal(p.Index) && sameNetworkInterface(c, p) {
+				matches[i] = j
+				claimed[j] = true
+				break
+			}
+		}
+	}
+
+	for i, p := range planned {
+		if !p.Index.IsNull() {
+			continue
+		}
+		for j, c := range current {
+			// interfaces at explicitly configured indexes are never taken over by others
+			if claimed[j] || (!c.Index.IsNull() && reserved[c.Index.ValueInt64()]) || !sameNetworkInterface(c, p) {
+				continue
+			}
+			matches[i] = j
+			claimed[j] = true
+			break
+		}
+	}
+
+	return matches
+}
+
+// planNetworkInterfaceChanges returns the smallest set of changes turning the current interfaces
+// into the planned ones, so that kept interfaces retain their addresses.
+func planNetworkInterfaceChanges(serverUUID string, current []upcloud.ServerInterface, planned []networkInterfaceModel) networkInterfaceChanges {
+	var changes networkInterfaceChanges
+
+	currentModels := make([]networkInterfaceModel, 0, len(current))
+	for _, iface := range current {
+		currentModels = append(currentModels, networkInterfaceFromServerInterface(iface))
+	}
+
+	kept := make([]bool, len(current))
+	for i, j := range matchNetworkInterfaces(currentModels, planned) {
+		r := buildNetworkInterfaceRequest(serverUUID, planned[i])
+		if j < 0 {
+			changes.create = append(changes.create, r)
+			continue
+		}
+
+		kept[j] = true
+		r.Index = current[j].Index
+		if interfacesEquals(current[j], r) &&
+			current[j].Bootable.Bool() == r.Bootable.Bool() &&
+			current[j].SourceIPFiltering.Bool() == r.SourceIPFiltering.Bool() {
+			continue
+		}
+		modify := request.ModifyNetworkInterfaceRequest{
+			ServerUUID:        serverUUID,
+			CurrentIndex:      current[j].Index,
+			SourceIPFiltering: r.SourceIPFiltering,
+			Bootable:          r.Bootable,
+		}
+		// addresses of other interfaces are assigned by UpCloud
+		if r.Type == upcloud.NetworkTypePrivate {
+			modify.IPAddresses = r.IPAddresses
+		}
+		changes.modify = append(changes.modify, modify)
+	}
+
+	for j, iface := range current {
+		if !kept[j] {
+			changes.delete = append(changes.delete, iface.Index)
+		}
+	}
+
+	return changes
+}
+
+func reconfigureServerNetworkInterfaces(ctx context.Context, svc *service.Service, data serverModel) error {
 	// assert server is stopped
 	s, err := svc.GetServerDetails(ctx, &request.GetServerDetailsRequest{
 		UUID: data.ID.ValueString(),
@@ -63,26 +222,22 @@ func reconfigureServerNetworkInterfaces(ctx context.Context, svc *service.Servic
 		return errors.New("server needs to be stopped to alter networks")
 	}
 
-	// Try to preserve public (IPv4 or IPv6) and utility network interfaces so that IPs doesn't change
-	preserveInterfaces := make(map[int]bool)
-	// flush interfaces
-	for i, n := range s.Networking.Interfaces {
-		if len(reqs) > i && interfacesEquals(n, reqs[i]) {
-			preserveInterfaces[n.Index] = true
-			continue
-		}
+	changes := planNetworkInterfaceChanges(data.ID.ValueString(), s.Networking.Interfaces, data.NetworkInterface)
+	// deleted interfaces first, so that their indexes can be reused
+	for _, index := range changes.delete {
 		if err := svc.DeleteNetworkInterface(ctx, &request.DeleteNetworkInterfaceRequest{
 			ServerUUID: data.ID.ValueString(),
-			Index:      n.Index,
+			Index:      index,
 		}); err != nil {
-			return fmt.Errorf("unable to delete interface #%d; %w", n.Index, err)
+			return fmt.Errorf("unable to delete interface #%d; %w", index, err)
 		}
 	}
-	// apply interfaces from state
-	for _, r := range reqs {
-		if _, ok := preserveInterfaces[r.Index]; ok {
-			continue
+	for _, r := range changes.modify {
+		if _, err := svc.ModifyNetworkInterface(ctx, &r); err != nil {
+			return fmt.Errorf("unable to modify interface #%d; %w", r.CurrentIndex, err)
 		}
+	}
+	for _, r := range changes.create {
 		if _, err := svc.CreateNetworkInterface(ctx, &r); err != nil {
 			return fmt.Errorf("unable to create interface #%d; %w", r.Index, err)
 		}
@@ -90,3 +245,203 @@ func reconfigureServerNetworkInterfaces(ctx context.Context, svc *service.Servic
 
 	return nil
 }
+
+func networkInterfaceFromServerInterface(iface upcloud.ServerInterface) networkInterfaceModel {
+	m := networkInterfaceModel{
+		Index:             types.Int64Value(int64(iface.Index)),
+		MacAddress:        types.StringValue(iface.MAC),
+		Type:              types.StringValue(iface.Type),
+		SourceIpFiltering: types.BoolValue(iface.SourceIPFiltering.Bool()),
+		Bootable:          types.BoolValue(iface.Bootable.Bool()),
+		Network:           types.StringValue(iface.Network),
+	}
+
+	// the first address is the primary one
+	m.AdditionalIPAddress = make([]additionalIPAddressModel, 0)
+	for j, ip := range iface.IPAddresses {
+		if j == 0 {
+			m.IpAddress = types.StringValue(ip.Address)
+			m.IpAddressFamily = types.StringValue(ip.Family)
+			m.IpAddressFloating = types.BoolValue(ip.Floating.Bool())
+			continue
+		}
+		// public interfaces can have floating IPs attached, which are not managed here
+		if iface.Type != upcloud.NetworkTypePrivate {
+			continue
+		}
+		m.AdditionalIPAddress = append(m.AdditionalIPAddress, additionalIPAddressModel{
+			IpAddress:         types.StringValue(ip.Address),
+			IpAddressFamily:   types.StringValue(ip.Family),
+			IpAddressFloating: types.BoolValue(ip.Floating.Bool()),
+		})
+	}
+
+	return m
+}
+
+// networkInterfacesFromServerDetails keeps the order of the prior interfaces so that the
+// refreshed list lines up with the configuration; unknown interfaces are appended.
+func networkInterfacesFromServerDetails(prior []networkInterfaceModel, interfaces []upcloud.ServerInterface) []networkInterfaceModel {
+	current := make([]networkInterfaceModel, 0, len(interfaces))
+	for _, iface := range interfaces {
+		current = append(current, networkInterfaceFromServerInterface(iface))
+	}
+
+	planned := make([]networkInterfaceModel, 0, len(prior))
+	for _, p := range prior {
+		// indexes of interfaces created during this apply were assigned by UpCloud
+		if p.Index.IsUnknown() {
+			p.Index = types.Int64Null()
+		}
+		planned = append(planned, p)
+	}
+
+	used := make([]bool, len(current))
+	response := make([]networkInterfaceModel, 0, len(current))
+	for _, j := range matchNetworkInterfaces(current, planned) {
+		if j < 0 {
+			continue
+		}
+		used[j] = true
+		response = append(response, current[j])
+	}
+	for j, iface := range current {
+		if !used[j] {
+			response = append(response, iface)
+		}
+	}
+
+	return response
+}
+
+// knownStringValue returns the value, or an empty string for unknown values.
+func knownStringValue(v types.String) string {
+	if v.IsUnknown() {
+		return ""
+	}
+	return v.ValueString()
+}
+
+func validateNetworkInterfaces(interfaces []networkInterfaceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	indexes := make(map[int64]bool)
+	for i, iface := range interfaces {
+		p := path.Root("network_interface").AtListIndex(i)
+		if !iface.Index.IsNull() && !iface.Index.IsUnknown() {
+			if indexes[iface.Index.ValueInt64()] {
+				diags.AddAttributeError(p.AtName("index"), "Invalid Attribute Value", fmt.Sprintf("Interface index %d is used more than once.", iface.Index.ValueInt64()))
+			}
+			indexes[iface.Index.ValueInt64()] = true
+		}
+		if iface.Type.IsUnknown() {
+			continue
+		}
+		// type defaults to public
+		ifaceType := upcloud.NetworkTypePublic
+		if !iface.Type.IsNull() {
+			ifaceType = iface.Type.ValueString()
+		}
+
+		if ifaceType == upcloud.NetworkTypePrivate && iface.Network.IsNull() {
+			diags.AddAttributeError(p.AtName("network"), "Missing Attribute Configuration", "`network` is required for `private` interfaces.")
+		}
+		if ifaceType != upcloud.NetworkTypePrivate && !iface.Network.IsNull() {
+			diags.AddAttributeError(p.AtName("network"), "Invalid Attribute Combination", fmt.Sprintf("`network` can only be set for `private` interfaces, got `%s`.", ifaceType))
+		}
+		if ifaceType != upcloud.NetworkTypePrivate && !iface.IpAddress.IsNull() {
+			diags.AddAttributeError(p.AtName("ip_address"), "Invalid Attribute Combination", fmt.Sprintf("`ip_address` can only be set for `private` interfaces, got `%s`.", ifaceType))
+		}
+		if ifaceType == upcloud.NetworkTypePublic && !iface.SourceIpFiltering.IsNull() && !iface.SourceIpFiltering.IsUnknown() && !iface.SourceIpFiltering.ValueBool() {
+			diags.AddAttributeError(p.AtName("source_ip_filtering"), "Invalid Attribute Combination", "`source_ip_filtering` can not be disabled for `public` interfaces.")
+		}
+		if ifaceType == upcloud.NetworkTypePublic && iface.Bootable.ValueBool() {
+			diags.AddAttributeError(p.AtName("bootable"), "Invalid Attribute Combination", "`bootable` can not be enabled for `public` interfaces.")
+		}
+
+		if ifaceType != upcloud.NetworkTypePrivate && len(iface.AdditionalIPAddress) > 0 {
+			diags.AddAttributeError(p.AtName("additional_ip_address"), "Invalid Attribute Combination", fmt.Sprintf("`additional_ip_address` can only be set for `private` interfaces, got `%s`.", ifaceType))
+		}
+		for j, ip := range iface.AdditionalIPAddress {
+			diags.Append(validateInterfaceIPAddress(p.AtName("additional_ip_address").AtListIndex(j).AtName("ip_address"), ip.IpAddressFamily, ip.IpAddress)...)
+		}
+		diags.Append(validateInterfaceIPAddress(p.AtName("ip_address"), iface.IpAddressFamily, iface.IpAddress)...)
+	}
+
+	return diags
+}
+
+// validateCreatedInterfaceIndexes checks the indexes configured for a new server, whose interfaces
+// are always numbered in order.
+func validateCreatedInterfaceIndexes(interfaces []networkInterfaceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for i, iface := range interfaces {
+		if iface.Index.IsNull() || iface.Index.IsUnknown() || iface.Index.ValueInt64() == int64(i+1) {
+			continue
+		}
+		diags.AddAttributeError(
+			path.Root("network_interface").AtListIndex(i).AtName("index"),
+			"Invalid Attribute Value",
+			fmt.Sprintf("Interfaces of a new server are numbered in order, expected index %d, got %d.", i+1, iface.Index.ValueInt64()),
+		)
+	}
+
+	return diags
+}
+
+// validateInterfaceIPAddress checks that a configured address is valid and of the configured family.
+func validateInterfaceIPAddress(p path.Path, family, address types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if address.IsNull() || address.IsUnknown() {
+		return diags
+	}
+	ip := net.ParseIP(address.ValueString())
+	if ip == nil {
+		diags.AddAttributeError(p, "Invalid IP Address", fmt.Sprintf("Expected a valid IP address, got %s.", address.ValueString()))
+		return diags
+	}
+	if family.IsUnknown() {
+		return diags
+	}
+	// ip_address_family defaults to IPv4
+	expected := upcloud.IPAddressFamilyIPv4
+	if !family.IsNull() {
+		expected = family.ValueString()
+	}
+	if ipAddressFamily(ip) != expected {
+		diags.AddAttributeError(p, "Invalid IP Address", fmt.Sprintf("Expected an %s address, got %s.", expected, address.ValueString()))
+	}
+
+	return diags
+}
+
+func ipAddressFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return upcloud.IPAddressFamilyIPv4
+	}
+	return upcloud.IPAddressFamilyIPv6
+}
+
+type interfaceAddress struct {
+	path    path.Path
+	address types.String
+}
+
+// changedInterfaceAddresses returns the configured addresses of an interface which differ from state,
+// all of them if the interface moves to another network.
+func changedInterfaceAddresses(p path.Path, state, config networkInterfaceModel) []interfaceAddress {
+	networkChanged := !config.Network.Equal(state.Network)
+
+	addresses := make([]interfaceAddress, 0)
+	if networkChanged || !config.IpAddress.Equal(state.IpAddress) {
+		addresses = append(addresses, interfaceAddress{p.AtName("ip_address"), config.IpAddress})
+	}
+	for i, ip := range config.AdditionalIPAddress {
+		if networkChanged || i >= len(state.AdditionalIPAddress) || !ip.IpAddress.Equal(state.AdditionalIPAddress[i].IpAddress) {
+			addresses = append(addresses, interfaceAddress{p.AtName("additional_ip_address").AtListIndex(i).AtName("ip_address"), ip.IpAddress})
+		}
+	}
+	return addresses
+}
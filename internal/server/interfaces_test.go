@@ -5,6 +5,7 @@ import (
 
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -77,6 +78,84 @@ func TestInterfacesEquals(t *testing.T) {
 			},
 			result: true,
 		},
+		{
+			name: "floating IP on public interface",
+			a: upcloud.ServerInterface{
+				Index: 0,
+				IPAddresses: []upcloud.IPAddress{
+					{Family: upcloud.IPAddressFamilyIPv4},
+					{Family: upcloud.IPAddressFamilyIPv4, Floating: upcloud.True},
+				},
+				Type: upcloud.NetworkTypePublic,
+			},
+			b: request.CreateNetworkInterfaceRequest{
+				Index: 0,
+				IPAddresses: []request.CreateNetworkInterfaceIPAddress{{
+					Family: upcloud.IPAddressFamilyIPv4,
+				}},
+				Type: upcloud.NetworkTypePublic,
+			},
+			result: true,
+		},
+		{
+			name: "additional address added",
+			a: upcloud.ServerInterface{
+				Index: 0,
+				IPAddresses: []upcloud.IPAddress{
+					{Family: upcloud.IPAddressFamilyIPv4, Address: "10.0.0.10"},
+				},
+				Type: upcloud.NetworkTypePrivate,
+			},
+			b: request.CreateNetworkInterfaceRequest{
+				Index: 0,
+				IPAddresses: []request.CreateNetworkInterfaceIPAddress{
+					{Family: upcloud.IPAddressFamilyIPv4, Address: "10.0.0.10"},
+					{Family: upcloud.IPAddressFamilyIPv4},
+				},
+				Type: upcloud.NetworkTypePrivate,
+			},
+			result: false,
+		},
+		{
+			name: "additional address difference",
+			a: upcloud.ServerInterface{
+				Index: 0,
+				IPAddresses: []upcloud.IPAddress{
+					{Family: upcloud.IPAddressFamilyIPv4, Address: "10.0.0.10"},
+					{Family: upcloud.IPAddressFamilyIPv4, Address: "10.0.0.11"},
+				},
+				Type: upcloud.NetworkTypePrivate,
+			},
+			b: request.CreateNetworkInterfaceRequest{
+				Index: 0,
+				IPAddresses: []request.CreateNetworkInterfaceIPAddress{
+					{Family: upcloud.IPAddressFamilyIPv4, Address: "10.0.0.10"},
+					{Family: upcloud.IPAddressFamilyIPv4, Address: "10.0.0.12"},
+				},
+				Type: upcloud.NetworkTypePrivate,
+			},
+			result: false,
+		},
+		{
+			name: "private network difference",
+			a: upcloud.ServerInterface{
+				Index: 0,
+				IPAddresses: []upcloud.IPAddress{{
+					Family: upcloud.IPAddressFamilyIPv4,
+				}},
+				Type:    upcloud.NetworkTypePrivate,
+				Network: "03000000-0000-4000-8000-000000000001",
+			},
+			b: request.CreateNetworkInterfaceRequest{
+				Index: 0,
+				IPAddresses: []request.CreateNetworkInterfaceIPAddress{{
+					Family: upcloud.IPAddressFamilyIPv4,
+				}},
+				Type:        upcloud.NetworkTypePrivate,
+				NetworkUUID: "03000000-0000-4000-8000-000000000002",
+			},
+			result: false,
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -84,3 +163,261 @@ func TestInterfacesEquals(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateNetworkInterfaces(t *testing.T) {
+	network := types.StringValue("03000000-0000-4000-8000-000000000001")
+
+	testCases := []struct {
+		name  string
+		iface networkInterfaceModel
+		error string
+	}{
+		{
+			name:  "public by default",
+			iface: networkInterfaceModel{},
+		},
+		{
+			name: "private with network and address",
+			iface: networkInterfaceModel{
+				Type:      types.StringValue(upcloud.NetworkTypePrivate),
+				Network:   network,
+				IpAddress: types.StringValue("10.0.0.10"),
+			},
+		},
+		{
+			name: "private without network",
+			iface: networkInterfaceModel{
+				Type: types.StringValue(upcloud.NetworkTypePrivate),
+			},
+			error: "`network` is required for `private` interfaces.",
+		},
+		{
+			name: "public with network",
+			iface: networkInterfaceModel{
+				Network: network,
+			},
+			error: "`network` can only be set for `private` interfaces, got `public`.",
+		},
+		{
+			name: "utility with address",
+			iface: networkInterfaceModel{
+				Type:      types.StringValue(upcloud.NetworkTypeUtility),
+				IpAddress: types.StringValue("10.0.0.10"),
+			},
+			error: "`ip_address` can only be set for `private` interfaces, got `utility`.",
+		},
+		{
+			name: "public without source ip filtering",
+			iface: networkInterfaceModel{
+				SourceIpFiltering: types.BoolValue(false),
+			},
+			error: "`source_ip_filtering` can not be disabled for `public` interfaces.",
+		},
+		{
+			name: "bootable utility",
+			iface: networkInterfaceModel{
+				Type:     types.StringValue(upcloud.NetworkTypeUtility),
+				Bootable: types.BoolValue(true),
+			},
+		},
+		{
+			name: "address family mismatch",
+			iface: networkInterfaceModel{
+				Type:      types.StringValue(upcloud.NetworkTypePrivate),
+				Network:   network,
+				IpAddress: types.StringValue("fd00::10"),
+			},
+			error: "Expected an IPv4 address, got fd00::10.",
+		},
+		{
+			name: "additional address on public interface",
+			iface: networkInterfaceModel{
+				AdditionalIPAddress: []additionalIPAddressModel{{}},
+			},
+			error: "`additional_ip_address` can only be set for `private` interfaces, got `public`.",
+		},
+		{
+			name: "invalid additional address",
+			iface: networkInterfaceModel{
+				Type:    types.StringValue(upcloud.NetworkTypePrivate),
+				Network: network,
+				AdditionalIPAddress: []additionalIPAddressModel{{
+					IpAddressFamily: types.StringValue(upcloud.IPAddressFamilyIPv6),
+					IpAddress:       types.StringValue("10.0.0.11"),
+				}},
+			},
+			error: "Expected an IPv6 address, got 10.0.0.11.",
+		},
+		{
+			name: "unknown network",
+			iface: networkInterfaceModel{
+				Type:    types.StringValue(upcloud.NetworkTypePrivate),
+				Network: types.StringUnknown(),
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			diags := validateNetworkInterfaces([]networkInterfaceModel{testCase.iface})
+			if testCase.error == "" {
+				assert.False(t, diags.HasError())
+				return
+			}
+			if assert.Len(t, diags, 1) {
+				assert.Equal(t, testCase.error, diags[0].Detail())
+			}
+		})
+	}
+}
+
+func TestPlanNetworkInterfaceChanges(t *testing.T) {
+	current := []upcloud.ServerInterface{
+		{
+			Index:             1,
+			Type:              upcloud.NetworkTypePublic,
+			IPAddresses:       []upcloud.IPAddress{{Family: upcloud.IPAddressFamilyIPv4, Address: "94.237.0.1"}},
+			SourceIPFiltering: upcloud.True,
+			Bootable:          upcloud.False,
+		},
+		{
+			Index:             2,
+			Type:              upcloud.NetworkTypeUtility,
+			IPAddresses:       []upcloud.IPAddress{{Family: upcloud.IPAddressFamilyIPv4, Address: "10.6.0.1"}},
+			SourceIPFiltering: upcloud.True,
+			Bootable:          upcloud.False,
+		},
+		{
+			Index:             3,
+			Type:              upcloud.NetworkTypePrivate,
+			Network:           "network",
+			IPAddresses:       []upcloud.IPAddress{{Family: upcloud.IPAddressFamilyIPv4, Address: "10.0.0.10"}},
+			SourceIPFiltering: upcloud.True,
+			Bootable:          upcloud.False,
+		},
+	}
+	public := networkInterfaceModel{
+		IpAddressFamily:   types.StringValue(upcloud.IPAddressFamilyIPv4),
+		Type:              types.StringValue(upcloud.NetworkTypePublic),
+		SourceIpFiltering: types.BoolValue(true),
+		Bootable:          types.BoolValue(false),
+	}
+	utility := public
+	utility.Type = types.StringValue(upcloud.NetworkTypeUtility)
+	private := public
+	private.Type = types.StringValue(upcloud.NetworkTypePrivate)
+	private.Network = types.StringValue("network")
+	publicIPv6 := public
+	publicIPv6.IpAddressFamily = types.StringValue(upcloud.IPAddressFamilyIPv6)
+	withIndex := func(m networkInterfaceModel, index int64) networkInterfaceModel {
+		m.Index = types.Int64Value(index)
+		return m
+	}
+	withNetwork := func(m networkInterfaceModel, network string) networkInterfaceModel {
+		m.Network = types.StringValue(network)
+		return m
+	}
+	withAddress := func(m networkInterfaceModel, address string) networkInterfaceModel {
+		m.IpAddress = types.StringValue(address)
+		return m
+	}
+
+	testCases := []struct {
+		name    string
+		planned []networkInterfaceModel
+		delete  []int
+		modify  []int
+		create  []int
+	}{
+		{
+			name:    "no changes",
+			planned: []networkInterfaceModel{public, utility, private},
+		},
+		{
+			name:    "reorder",
+			planned: []networkInterfaceModel{private, public, utility},
+		},
+		{
+			name:    "insert at the top",
+			planned: []networkInterfaceModel{publicIPv6, public, utility, private},
+			create:  []int{0},
+		},
+		{
+			name:    "remove from the middle",
+			planned: []networkInterfaceModel{public, private},
+			delete:  []int{2},
+		},
+		{
+			name:    "move to another network",
+			planned: []networkInterfaceModel{public, utility, withNetwork(private, "other")},
+			delete:  []int{3},
+			create:  []int{0},
+		},
+		{
+			name:    "private address change",
+			planned: []networkInterfaceModel{public, utility, withAddress(private, "10.0.0.20")},
+			modify:  []int{3},
+		},
+		{
+			name:    "explicit index matches",
+			planned: []networkInterfaceModel{withIndex(public, 1), withIndex(private, 3), utility},
+		},
+		{
+			name:    "explicit index replaces other interface",
+			planned: []networkInterfaceModel{public, withIndex(private, 2)},
+			delete:  []int{2, 3},
+			create:  []int{2},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			changes := planNetworkInterfaceChanges("server", current, testCase.planned)
+			assert.Equal(t, testCase.delete, changes.delete)
+
+			var modify, create []int
+			for _, r := range changes.modify {
+				modify = append(modify, r.CurrentIndex)
+			}
+			for _, r := range changes.create {
+				create = append(create, r.Index)
+			}
+			assert.Equal(t, testCase.modify, modify)
+			assert.Equal(t, testCase.create, create)
+		})
+	}
+}
+
+func TestNetworkInterfacesFromServerDetails(t *testing.T) {
+	interfaces := []upcloud.ServerInterface{
+		{Index: 1, MAC: "mac1", Type: upcloud.NetworkTypePublic, IPAddresses: []upcloud.IPAddress{{Family: upcloud.IPAddressFamilyIPv4}}},
+		{Index: 2, MAC: "mac2", Type: upcloud.NetworkTypePrivate, Network: "network", IPAddresses: []upcloud.IPAddress{{Family: upcloud.IPAddressFamilyIPv4}}},
+		{Index: 3, MAC: "mac3", Type: upcloud.NetworkTypePublic, IPAddresses: []upcloud.IPAddress{{Family: upcloud.IPAddressFamilyIPv6}}},
+		{Index: 4, MAC: "mac4", Type: upcloud.NetworkTypeUtility, IPAddresses: []upcloud.IPAddress{{Family: upcloud.IPAddressFamilyIPv4}}},
+	}
+	prior := []networkInterfaceModel{
+		// created during this apply
+		{
+			Index:           types.Int64Unknown(),
+			Type:            types.StringValue(upcloud.NetworkTypePublic),
+			IpAddressFamily: types.StringValue(upcloud.IPAddressFamilyIPv6),
+		},
+		{
+			Index:           types.Int64Value(2),
+			Type:            types.StringValue(upcloud.NetworkTypePrivate),
+			IpAddressFamily: types.StringValue(upcloud.IPAddressFamilyIPv4),
+			Network:         types.StringValue("network"),
+		},
+		{
+			Index:           types.Int64Value(1),
+			Type:            types.StringValue(upcloud.NetworkTypePublic),
+			IpAddressFamily: types.StringValue(upcloud.IPAddressFamilyIPv4),
+		},
+	}
+
+	result := networkInterfacesFromServerDetails(prior, interfaces)
+
+	macs := make([]string, 0, len(result))
+	for _, iface := range result {
+		macs = append(macs, iface.MacAddress.ValueString())
+	}
+	assert.Equal(t, []string{"mac3", "mac2", "mac1", "mac4"}, macs)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func buildLabelsForServer(ctx context.Context, labels types.Map) (*upcloud.LabelSlice, diag.Diagnostics) {
+	if labels.IsNull() || labels.IsUnknown() {
+		return nil, nil
+	}
+
+	values := make(map[string]string, len(labels.Elements()))
+	if diags := labels.ElementsAs(ctx, &values, false); diags.HasError() {
+		return nil, diags
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	response := make(upcloud.LabelSlice, 0, len(keys))
+	for _, k := range keys {
+		response = append(response, upcloud.Label{Key: k, Value: values[k]})
+	}
+
+	return &response, nil
+}
+
+func labelsFromServerDetails(prior types.Map, labels upcloud.LabelSlice) (types.Map, diag.Diagnostics) {
+	values := make(map[string]string, len(labels))
+	for _, label := range labels {
+		// system labels can not be managed by users
+		if strings.HasPrefix(label.Key, "_") {
+			continue
+		}
+		values[label.Key] = label.Value
+	}
+
+	// keep an explicitly empty map instead of turning it into null
+	if len(values) == 0 && (prior.IsNull() || len(prior.Elements()) == 0) {
+		return prior, nil
+	}
+
+	return types.MapValueFrom(context.Background(), types.StringType, values)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/client"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+)
+
+func buildLoginUserForServer(login []loginModel) (*request.LoginUser, string) {
+	if len(login) == 0 {
+		return nil, ""
+	}
+
+	keys := make(request.SSHKeySlice, 0, len(login[0].Keys))
+	for _, key := range login[0].Keys {
+		keys = append(keys, key.ValueString())
+	}
+
+	createPassword := "no"
+	if login[0].CreatePassword.ValueBool() {
+		createPassword = "yes"
+	}
+
+	return &request.LoginUser{
+		Username:       login[0].User.ValueString(),
+		SSHKeys:        keys,
+		CreatePassword: createPassword,
+	}, login[0].PasswordDelivery.ValueString()
+}
+
+// isPasswordReturned reports whether the generated password is returned by the API
+// instead of being delivered by email or SMS.
+func isPasswordReturned(login []loginModel) bool {
+	return len(login) > 0 &&
+		login[0].CreatePassword.ValueBool() &&
+		login[0].PasswordDelivery.ValueString() == request.PasswordDeliveryNone
+}
+
+// createServerWithPassword creates the server like service.CreateServer does, but also
+// decodes the generated password of the default user, which service.CreateServer drops.
+func createServerWithPassword(ctx context.Context, c service.Client, r *request.CreateServerRequest) (*upcloud.ServerDetails, string, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	res, err := c.Post(ctx, r.RequestURL(), payload)
+	if err != nil {
+		var clientErr *client.Error
+		if errors.As(err, &clientErr) && clientErr.Type == client.ErrorTypeProblem {
+			problem := &upcloud.Problem{}
+			if json.Unmarshal(clientErr.ResponseBody, problem) == nil {
+				return nil, "", problem
+			}
+		}
+		return nil, "", err
+	}
+
+	details := &upcloud.ServerDetails{}
+	if err := json.Unmarshal(res, details); err != nil {
+		return nil, "", err
+	}
+
+	v := struct {
+		Server struct {
+			Password string `json:"password"`
+		} `json:"server"`
+	}{}
+	if err := json.Unmarshal(res, &v); err != nil {
+		return nil, "", err
+	}
+
+	return details, v.Server.Password, nil
+}
@@ -2,40 +2,216 @@ package server
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-type resetValueWhenFamilyChanges struct{}
+// useMatchingInterfaceState matches interfaces by identity rather than by position, so that adding,
+// removing or reordering interfaces only resets the values of the interfaces which actually change.
+type useMatchingInterfaceState struct{}
 
-func (m resetValueWhenFamilyChanges) Description(_ context.Context) string {
-	return "Resets value when ip_address_family changes; otherwise, keeps the prior state value."
+func (m useMatchingInterfaceState) Description(_ context.Context) string {
+	return "Keeps the prior state value of the matching interface; resets the value for new or replaced interfaces."
 }
 
-func (m resetValueWhenFamilyChanges) MarkdownDescription(_ context.Context) string {
-	return "Resets value when `ip_address_family` changes; otherwise, keeps the prior state value."
+func (m useMatchingInterfaceState) MarkdownDescription(_ context.Context) string {
+	return "Keeps the prior state value of the interface matched by `index`, or by `type`, `network` and `ip_address_family`; resets the value for new or replaced interfaces."
 }
 
-func (m resetValueWhenFamilyChanges) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
-	if !req.ConfigValue.IsNull() && !req.ConfigValue.IsUnknown() {
-		resp.PlanValue = req.ConfigValue
+func (m useMatchingInterfaceState) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if !req.ConfigValue.IsNull() || req.State.Raw.IsNull() {
 		return
 	}
 
-	var priorFamily, configFamily types.String
-	if diags := req.State.GetAttribute(ctx, req.Path.ParentPath().AtName("ip_address_family"), &priorFamily); diags.HasError() {
-		resp.Diagnostics.Append(diags...)
+	prior, ok, diags := matchingInterfacePath(ctx, req.Path, req.Config, req.State)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() {
+		return
+	}
+	if !ok {
+		resp.PlanValue = types.StringUnknown()
+		return
+	}
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, prior, &resp.PlanValue)...)
+}
+
+func (m useMatchingInterfaceState) PlanModifyBool(ctx context.Context, req planmodifier.BoolRequest, resp *planmodifier.BoolResponse) {
+	if !req.ConfigValue.IsNull() || req.State.Raw.IsNull() {
 		return
 	}
-	if diags := req.Config.GetAttribute(ctx, req.Path.ParentPath().AtName("ip_address_family"), &configFamily); diags.HasError() {
+
+	prior, ok, diags := matchingInterfacePath(ctx, req.Path, req.Config, req.State)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() {
+		return
+	}
+	if !ok {
+		resp.PlanValue = types.BoolUnknown()
+		return
+	}
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, prior, &resp.PlanValue)...)
+}
+
+func (m useMatchingInterfaceState) PlanModifyInt64(ctx context.Context, req planmodifier.Int64Request, resp *planmodifier.Int64Response) {
+	if !req.ConfigValue.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	prior, ok, diags := matchingInterfacePath(ctx, req.Path, req.Config, req.State)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() {
+		return
+	}
+	if !ok {
+		resp.PlanValue = types.Int64Unknown()
+		return
+	}
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, prior, &resp.PlanValue)...)
+}
+
+// matchingInterfacePath returns the path of the attribute within the prior state of the interface
+// matching the configured one, or false when the interface is new.
+func matchingInterfacePath(ctx context.Context, p path.Path, config tfsdk.Config, state tfsdk.State) (path.Path, bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	// network_interface[i].<attribute> or network_interface[i].additional_ip_address[j].<attribute>
+	steps := p.Steps()
+	i, ok := steps[1].(path.PathStepElementKeyInt)
+	if !ok {
+		return p, false, diags
+	}
+
+	var configInterfaces, stateInterfaces []networkInterfaceModel
+	known, d := getKnownList(ctx, config, path.Root("network_interface"), &configInterfaces)
+	diags.Append(d...)
+	diags.Append(state.GetAttribute(ctx, path.Root("network_interface"), &stateInterfaces)...)
+	if diags.HasError() || !known {
+		return p, false, diags
+	}
+
+	j := matchNetworkInterfaces(stateInterfaces, configInterfaces)[i]
+	if j < 0 {
+		return p, false, diags
+	}
+
+	prior := path.Root("network_interface").AtListIndex(j)
+	for _, step := range steps[2:] {
+		switch s := step.(type) {
+		case path.PathStepAttributeName:
+			prior = prior.AtName(string(s))
+		case path.PathStepElementKeyInt:
+			// additional addresses are matched by position within the interface
+			if int(s) >= len(stateInterfaces[j].AdditionalIPAddress) {
+				return p, false, diags
+			}
+			prior = prior.AtListIndex(int(s))
+		}
+	}
+	return prior, true, diags
+}
+
+// useMatchingStorageDeviceState matches storage devices by identity rather than by position, so that
+// removing or reordering devices never hands the storage of one device to another.
+type useMatchingStorageDeviceState struct{}
+
+func (m useMatchingStorageDeviceState) Description(_ context.Context) string {
+	return "Keeps the prior state value of the matching storage device; resets the value when a new storage has to be created for the device."
+}
+
+func (m useMatchingStorageDeviceState) MarkdownDescription(_ context.Context) string {
+	return "Keeps the prior state value of the storage device matched by `storage`, `title`, `address` or `size`; resets the value when a new storage has to be created for the device."
+}
+
+func (m useMatchingStorageDeviceState) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if !req.ConfigValue.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	// storage_devices[i].<attribute>
+	steps := req.Path.Steps()
+	i, ok := steps[1].(path.PathStepElementKeyInt)
+	if !ok {
+		return
+	}
+	name, ok := steps[2].(path.PathStepAttributeName)
+	if !ok {
+		return
+	}
+
+	var configDevices, stateDevices []storageDeviceModel
+	known, diags := getKnownList(ctx, req.Config, path.Root("storage_devices"), &configDevices)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("storage_devices"), &stateDevices)...)
+	if resp.Diagnostics.HasError() || !known {
+		return
+	}
+
+	j := matchStorageDevices(stateDevices, configDevices)[i]
+	if j < 0 {
+		resp.PlanValue = types.StringUnknown()
+		return
+	}
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("storage_devices").AtListIndex(j).AtName(string(name)), &resp.PlanValue)...)
+}
+
+type defaultTitleFromHostname struct{}
+
+func (m defaultTitleFromHostname) Description(_ context.Context) string {
+	return "Defaults to \"<hostname> (terraform resource)\" when title is not configured."
+}
+
+func (m defaultTitleFromHostname) MarkdownDescription(_ context.Context) string {
+	return "Defaults to `<hostname> (terraform resource)` when `title` is not configured."
+}
+
+func (m defaultTitleFromHostname) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+
+	var hostname types.String
+	if diags := req.Plan.GetAttribute(ctx, path.Root("hostname"), &hostname); diags.HasError() {
 		resp.Diagnostics.Append(diags...)
 		return
 	}
 
-	if !priorFamily.Equal(configFamily) {
+	if hostname.IsUnknown() {
 		resp.PlanValue = types.StringUnknown()
-	} else {
-		resp.PlanValue = req.StateValue
+		return
 	}
+	resp.PlanValue = types.StringValue(defaultServerTitle(hostname.ValueString()))
+}
+
+func defaultServerTitle(hostname string) string {
+	return fmt.Sprintf("%s %s", hostname, "(terraform resource)")
+}
+
+const requiresReplaceUnlessImportedDescription = "Requires replacement when changed, unless the server was imported and the value was never known."
+
+// Values which are only used when the server is created (e.g. login) can not be read back from the API,
+// so configuring them for an imported server must not replace it.
+func requiresReplaceUnlessImportedString(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+	imported, diags := wasImported(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	resp.RequiresReplace = !imported || !req.StateValue.IsNull()
+}
+
+func requiresReplaceUnlessImportedInt64(ctx context.Context, req planmodifier.Int64Request, resp *int64planmodifier.RequiresReplaceIfFuncResponse) {
+	imported, diags := wasImported(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	resp.RequiresReplace = !imported || !req.StateValue.IsNull()
+}
+
+func requiresReplaceUnlessImportedList(ctx context.Context, req planmodifier.ListRequest, resp *listplanmodifier.RequiresReplaceIfFuncResponse) {
+	imported, diags := wasImported(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	resp.RequiresReplace = !imported || len(req.StateValue.Elements()) > 0
 }
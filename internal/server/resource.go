@@ -3,31 +3,42 @@ package server
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/upcloud-terraform-provider-server/internal/utils"
 
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 var (
-	_ resource.Resource                = &serverResource{}
-	_ resource.ResourceWithConfigure   = &serverResource{}
-	_ resource.ResourceWithImportState = &serverResource{}
+	_ resource.Resource                   = &serverResource{}
+	_ resource.ResourceWithConfigure      = &serverResource{}
+	_ resource.ResourceWithImportState    = &serverResource{}
+	_ resource.ResourceWithValidateConfig = &serverResource{}
+	_ resource.ResourceWithModifyPlan     = &serverResource{}
+	_ resource.ResourceWithUpgradeState   = &serverResource{}
 )
 
 func NewServerResource() resource.Resource {
@@ -35,14 +46,53 @@ func NewServerResource() resource.Resource {
 }
 
 type serverResource struct {
-	client *service.Service
+	client    *service.Service
+	apiClient service.Client
 }
 
 type serverModel struct {
-	ID               types.String            `tfsdk:"id"`
-	Hostname         types.String            `tfsdk:"hostname"`
-	Zone             types.String            `tfsdk:"zone"`
-	NetworkInterface []networkInterfaceModel `tfsdk:"network_interface"`
+	ID                   types.String            `tfsdk:"id"`
+	Hostname             types.String            `tfsdk:"hostname"`
+	Zone                 types.String            `tfsdk:"zone"`
+	ZoneType             types.String            `tfsdk:"zone_type"`
+	Title                types.String            `tfsdk:"title"`
+	Plan                 types.String            `tfsdk:"plan"`
+	CPU                  types.Int64             `tfsdk:"cpu"`
+	Mem                  types.Int64             `tfsdk:"mem"`
+	PowerState           types.String            `tfsdk:"power_state"`
+	ServerGroup          types.String            `tfsdk:"server_group"`
+	Host                 types.Int64             `tfsdk:"host"`
+	AvoidHost            types.Int64             `tfsdk:"avoid_host"`
+	CurrentHost          types.Int64             `tfsdk:"current_host"`
+	Firewall             types.Bool              `tfsdk:"firewall"`
+	FirewallRule         []firewallRuleModel     `tfsdk:"firewall_rule"`
+	SimpleBackup         []simpleBackupModel     `tfsdk:"simple_backup"`
+	BootOrder            types.String            `tfsdk:"boot_order"`
+	NICModel             types.String            `tfsdk:"nic_model"`
+	VideoModel           types.String            `tfsdk:"video_model"`
+	Timezone             types.String            `tfsdk:"timezone"`
+	RemoteAccessEnabled  types.Bool              `tfsdk:"remote_access_enabled"`
+	RemoteAccessType     types.String            `tfsdk:"remote_access_type"`
+	RemoteAccessPassword types.String            `tfsdk:"remote_access_password"`
+	RemoteAccessHost     types.String            `tfsdk:"remote_access_host"`
+	RemoteAccessPort     types.Int64             `tfsdk:"remote_access_port"`
+	StopType             types.String            `tfsdk:"stop_type"`
+	ShutdownTimeout      types.Int64             `tfsdk:"shutdown_timeout"`
+	AllowRestart         types.Bool              `tfsdk:"allow_restart"`
+	DeleteStorages       types.Bool              `tfsdk:"delete_storages"`
+	DeleteBackups        types.String            `tfsdk:"delete_backups"`
+	RollbackOnFailure    types.Bool              `tfsdk:"rollback_on_failure"`
+	Metadata             types.Bool              `tfsdk:"metadata"`
+	UserData             types.String            `tfsdk:"user_data"`
+	Labels               types.Map               `tfsdk:"labels"`
+	Tags                 types.Set               `tfsdk:"tags"`
+	NetworkInterface     []networkInterfaceModel `tfsdk:"network_interface"`
+	StorageDevices       []storageDeviceModel    `tfsdk:"storage_devices"`
+	Login                []loginModel            `tfsdk:"login"`
+
+	DefaultUserPassword types.String `tfsdk:"default_user_password"`
+
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
 type networkInterfaceModel struct {
@@ -54,16 +104,62 @@ type networkInterfaceModel struct {
 	Network           types.String `tfsdk:"network"`
 	SourceIpFiltering types.Bool   `tfsdk:"source_ip_filtering"`
 	Bootable          types.Bool   `tfsdk:"bootable"`
-	//additional_ip_address is only for private networks, so I skip it
+	Index             types.Int64  `tfsdk:"index"`
+
+	AdditionalIPAddress []additionalIPAddressModel `tfsdk:"additional_ip_address"`
+}
+
+type additionalIPAddressModel struct {
+	IpAddressFamily   types.String `tfsdk:"ip_address_family"`
+	IpAddress         types.String `tfsdk:"ip_address"`
+	IpAddressFloating types.Bool   `tfsdk:"ip_address_floating"`
+}
+
+type storageDeviceModel struct {
+	Storage types.String `tfsdk:"storage"`
+	Address types.String `tfsdk:"address"`
+	Type    types.String `tfsdk:"type"`
+	Tier    types.String `tfsdk:"tier"`
+	Size    types.Int64  `tfsdk:"size"`
+	Title   types.String `tfsdk:"title"`
+}
+
+type firewallRuleModel struct {
+	Direction               types.String `tfsdk:"direction"`
+	Action                  types.String `tfsdk:"action"`
+	Family                  types.String `tfsdk:"family"`
+	Protocol                types.String `tfsdk:"protocol"`
+	SourceAddressStart      types.String `tfsdk:"source_address_start"`
+	SourceAddressEnd        types.String `tfsdk:"source_address_end"`
+	SourcePortStart         types.String `tfsdk:"source_port_start"`
+	SourcePortEnd           types.String `tfsdk:"source_port_end"`
+	DestinationAddressStart types.String `tfsdk:"destination_address_start"`
+	DestinationAddressEnd   types.String `tfsdk:"destination_address_end"`
+	DestinationPortStart    types.String `tfsdk:"destination_port_start"`
+	DestinationPortEnd      types.String `tfsdk:"destination_port_end"`
+	Comment                 types.String `tfsdk:"comment"`
+}
+
+type simpleBackupModel struct {
+	Plan types.String `tfsdk:"plan"`
+	Time types.String `tfsdk:"time"`
+}
+
+type loginModel struct {
+	User             types.String   `tfsdk:"user"`
+	Keys             []types.String `tfsdk:"keys"`
+	CreatePassword   types.Bool     `tfsdk:"create_password"`
+	PasswordDelivery types.String   `tfsdk:"password_delivery"`
 }
 
 func (r *serverResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_server"
 }
 
-func (r *serverResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *serverResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		MarkdownDescription: "The UpCloud server resource allows the creation, update, and deletion of a cloud server.",
+		Version:             serverSchemaVersion,
 
 		Attributes: map[string]schema.Attribute{
 			"hostname": schema.StringAttribute{
@@ -80,6 +176,268 @@ func (r *serverResource) Schema(_ context.Context, _ resource.SchemaRequest, res
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"zone_type": schema.StringAttribute{
+				MarkdownDescription: "Restricts `zone` to `public` zones or `private` cloud zones. Any zone is accepted when not set.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(zoneTypePublic, zoneTypePrivate),
+				},
+			},
+			"title": schema.StringAttribute{
+				MarkdownDescription: "A short, informational description of the server. Defaults to `<hostname> (terraform resource)`.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, 255),
+				},
+				PlanModifiers: []planmodifier.String{
+					defaultTitleFromHostname{},
+				},
+			},
+			"plan": schema.StringAttribute{
+				MarkdownDescription: "The pricing plan of the server, e.g. `1xCPU-2GB` or `custom`.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"cpu": schema.Int64Attribute{
+				MarkdownDescription: "The number of CPU cores of the server.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"mem": schema.Int64Attribute{
+				MarkdownDescription: "The amount of memory of the server in megabytes.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"power_state": schema.StringAttribute{
+				MarkdownDescription: "The desired power state of the server, `started` or `stopped`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(upcloud.ServerStateStarted),
+				Validators: []validator.String{
+					stringvalidator.OneOf(upcloud.ServerStateStarted, upcloud.ServerStateStopped),
+				},
+			},
+			"server_group": schema.StringAttribute{
+				MarkdownDescription: "The UUID of a server group the server is a member of, e.g. for anti-affinity.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"host": schema.Int64Attribute{
+				MarkdownDescription: "The ID of the host to create the server on. Only available on private cloud hosts. Changing it replaces the server.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+					int64validator.ConflictsWith(path.MatchRoot("avoid_host")),
+				},
+				// the requested host is not returned by the API, only the current one
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplaceIf(
+						requiresReplaceUnlessImportedInt64,
+						requiresReplaceUnlessImportedDescription,
+						requiresReplaceUnlessImportedDescription,
+					),
+				},
+			},
+			"avoid_host": schema.Int64Attribute{
+				MarkdownDescription: "The ID of a host the server should not be placed on. Applied when the server is created or started by the provider.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"current_host": schema.Int64Attribute{
+				MarkdownDescription: "The ID of the host the server is currently running on.",
+				Computed:            true,
+			},
+			"firewall": schema.BoolAttribute{
+				MarkdownDescription: "Whether the UpCloud firewall is enabled for the server.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"boot_order": schema.StringAttribute{
+				MarkdownDescription: "The boot device order, a comma separated list of `disk`, `cdrom` and `network`, e.g. `cdrom,disk`.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(bootOrderRegexp, "must be a comma separated list of `disk`, `cdrom` and `network`"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"nic_model": schema.StringAttribute{
+				MarkdownDescription: "The model of the server's network interfaces, `virtio`, `e1000` or `rtl8139`. Changing it stops and starts the server.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(upcloud.NICModelVirtio, upcloud.NICModelE1000, upcloud.NICModelRTL8139),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"video_model": schema.StringAttribute{
+				MarkdownDescription: "The model of the server's video interface, `vga` or `cirrus`. Changing it stops and starts the server.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(upcloud.VideoModelVGA, upcloud.VideoModelCirrus),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"timezone": schema.StringAttribute{
+				MarkdownDescription: "The timezone of the server's hardware clock, e.g. `Europe/Helsinki`. Changing it stops and starts the server.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"remote_access_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Whether remote console access (VNC or SPICE) is enabled.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"remote_access_type": schema.StringAttribute{
+				MarkdownDescription: "The remote console access type, `vnc` or `spice`.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(upcloud.RemoteAccessTypeVNC, upcloud.RemoteAccessTypeSPICE),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"remote_access_password": schema.StringAttribute{
+				MarkdownDescription: "The password for remote console access. Generated by UpCloud when not set.",
+				Optional:            true,
+				Computed:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, 8),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"remote_access_host": schema.StringAttribute{
+				MarkdownDescription: "The hostname for remote console access.",
+				Computed:            true,
+			},
+			"remote_access_port": schema.Int64Attribute{
+				MarkdownDescription: "The port for remote console access.",
+				Computed:            true,
+			},
+			"stop_type": schema.StringAttribute{
+				MarkdownDescription: "How the server is stopped when a change requires it or on delete: `soft` (ACPI shutdown, then hard stop after `shutdown_timeout`) or `hard`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(upcloud.StopTypeSoft),
+				Validators: []validator.String{
+					stringvalidator.OneOf(upcloud.StopTypeSoft, upcloud.StopTypeHard),
+				},
+			},
+			"shutdown_timeout": schema.Int64Attribute{
+				MarkdownDescription: "Seconds to wait for a `soft` stop before the server is hard stopped.",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(defaultShutdownTimeout),
+				Validators: []validator.Int64{
+					int64validator.Between(1, 600),
+				},
+			},
+			"allow_restart": schema.BoolAttribute{
+				MarkdownDescription: "Whether the server may be stopped and started again to apply changes which require it. When `false` such changes fail at plan time.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"delete_storages": schema.BoolAttribute{
+				MarkdownDescription: "Whether the storages of the server are deleted together with it. When `false` only the server is deleted and its storages are kept.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"delete_backups": schema.StringAttribute{
+				MarkdownDescription: "What happens to the backups of the deleted storages: `keep`, `keep_latest` or `delete`. Requires `delete_storages` to be `true`. Defaults to keeping the backups.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(
+						string(request.DeleteStorageBackupsModeKeep),
+						string(request.DeleteStorageBackupsModeKeepLatest),
+						string(request.DeleteStorageBackupsModeDelete),
+					),
+				},
+			},
+			"rollback_on_failure": schema.BoolAttribute{
+				MarkdownDescription: "Whether a server which fails to be fully created is deleted again. When `false` the server is kept in state as tainted and replaced on the next apply.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"metadata": schema.BoolAttribute{
+				MarkdownDescription: "Whether the metadata service is enabled. Required by cloud-init based templates and `user_data`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"user_data": schema.StringAttribute{
+				MarkdownDescription: "A script or `#cloud-config` document run on the first boot of the server. Changing it replaces the server.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+					userDataSizeValidator{},
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIf(
+						requiresReplaceUnlessImportedString,
+						requiresReplaceUnlessImportedDescription,
+						requiresReplaceUnlessImportedDescription,
+					),
+				},
+			},
+			"labels": schema.MapAttribute{
+				MarkdownDescription: "Key-value pairs to classify the server.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(
+						stringvalidator.LengthBetween(2, 32),
+						stringvalidator.RegexMatches(labelKeyRegexp, "must contain only letters, numbers, `_` and `-`"),
+						stringvalidator.RegexMatches(labelKeyNotReservedRegexp, "must not start with `_`, which is reserved for system labels"),
+					),
+					mapvalidator.ValueStringsAre(
+						stringvalidator.LengthAtMost(255),
+					),
+				},
+			},
+			"tags": schema.SetAttribute{
+				MarkdownDescription: "The server related tags. Missing tags are created, but never deleted as other servers may use them.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.Set{
+					setvalidator.ValueStringsAre(
+						stringvalidator.LengthBetween(1, 32),
+					),
+				},
+			},
 			"id": schema.StringAttribute{
 				MarkdownDescription: "The unique identifier (UUID) of the UpCloud server.",
 				Computed:            true,
@@ -87,6 +445,14 @@ func (r *serverResource) Schema(_ context.Context, _ resource.SchemaRequest, res
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"default_user_password": schema.StringAttribute{
+				MarkdownDescription: "The generated password of the default user. Only set when `login.create_password` is `true` and `login.password_delivery` is `none`.",
+				Computed:            true,
+				Sensitive:           true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
 		},
 		Blocks: map[string]schema.Block{
 			"network_interface": schema.ListNestedBlock{
@@ -108,64 +474,298 @@ func (r *serverResource) Schema(_ context.Context, _ resource.SchemaRequest, res
 								stringplanmodifier.UseStateForUnknown(),
 							},
 						},
-						// for public type network ip_address can not be set,
+						// only private interfaces can have a static address
 						"ip_address": schema.StringAttribute{
-							MarkdownDescription: "The assigned primary IP address.",
-							//Optional:            true,
-							Computed: true,
+							MarkdownDescription: "The primary IP address of this interface. Can only be set for `private` interfaces, where it has to be within the network's CIDR; otherwise it is assigned by UpCloud.",
+							Optional:            true,
+							Computed:            true,
 							PlanModifiers: []planmodifier.String{
-								stringplanmodifier.UseStateForUnknown(),
-								resetValueWhenFamilyChanges{},
+								useMatchingInterfaceState{},
 							},
 						},
 						"ip_address_floating": schema.BoolAttribute{
 							MarkdownDescription: "`true` if the primary IP address is a floating IP address.",
 							Computed:            true,
 							PlanModifiers: []planmodifier.Bool{
-								boolplanmodifier.UseStateForUnknown(),
+								useMatchingInterfaceState{},
 							},
 						},
 						"mac_address": schema.StringAttribute{
 							MarkdownDescription: "The MAC address assigned to this interface.",
 							Computed:            true,
 							PlanModifiers: []planmodifier.String{
-								stringplanmodifier.UseStateForUnknown(),
-								resetValueWhenFamilyChanges{},
+								useMatchingInterfaceState{},
 							},
 						},
 						"type": schema.StringAttribute{
-							MarkdownDescription: "Supported value: `public`.",
+							MarkdownDescription: "Network type of the interface. Supported values: `public`, `private` and `utility`.",
+							Optional:            true,
 							Computed:            true,
-							Default:             stringdefault.StaticString("public"),
+							Default:             stringdefault.StaticString(upcloud.NetworkTypePublic),
 							Validators: []validator.String{
 								stringvalidator.OneOf(
-									"public",
+									upcloud.NetworkTypePublic,
+									upcloud.NetworkTypePrivate,
+									upcloud.NetworkTypeUtility,
 								),
 							},
 						},
-						// for public type network can not be set by user
+						// for public and utility type network is assigned by UpCloud
 						"network": schema.StringAttribute{
-							MarkdownDescription: "The unique ID of a network to attach this network to.",
-							//Optional:            true,
-							Computed: true,
+							MarkdownDescription: "The unique ID of a network to attach this network to. Only used with `private` interfaces.",
+							Optional:            true,
+							Computed:            true,
 							PlanModifiers: []planmodifier.String{
-								stringplanmodifier.UseStateForUnknown(),
-								resetValueWhenFamilyChanges{},
+								useMatchingInterfaceState{},
 							},
 						},
-						// for public type source_ip_filtering can only be true, so user can not change it
+						// for public type source_ip_filtering can only be true
 						"source_ip_filtering": schema.BoolAttribute{
-							MarkdownDescription: "`true` if source IP filtering is enabled on the interface.",
+							MarkdownDescription: "`true` if source IP filtering is enabled on the interface. Can only be disabled for `private` and `utility` interfaces.",
+							Optional:            true,
 							Computed:            true,
-							//Optional:            true,
-							Default: booldefault.StaticBool(true),
+							Default:             booldefault.StaticBool(true),
 						},
-						// for public type bootable can only be false, so user can not change it
+						// for public type bootable can only be false
 						"bootable": schema.BoolAttribute{
-							MarkdownDescription: "`true` if the interface should be used for network booting.",
+							MarkdownDescription: "`true` if the interface should be used for network booting. Can only be enabled for `private` and `utility` interfaces.",
+							Optional:            true,
+							Computed:            true,
+							Default:             booldefault.StaticBool(false),
+						},
+						"index": schema.Int64Attribute{
+							MarkdownDescription: "The index of the interface on the server. Existing interfaces are matched by `index` when it is set, otherwise by `type`, `network` and `ip_address_family`, so that adding, removing or reordering interfaces keeps the others and their IP addresses intact. Assigned by UpCloud when not set.",
+							Optional:            true,
+							Computed:            true,
+							Validators: []validator.Int64{
+								int64validator.AtLeast(1),
+							},
+							PlanModifiers: []planmodifier.Int64{
+								useMatchingInterfaceState{},
+							},
+						},
+					},
+					Blocks: map[string]schema.Block{
+						"additional_ip_address": schema.ListNestedBlock{
+							MarkdownDescription: "Additional IP addresses of the interface. Only supported for `private` interfaces.",
+							Validators: []validator.List{
+								listvalidator.SizeAtMost(maxAdditionalIPAddresses),
+							},
+							NestedObject: schema.NestedBlockObject{
+								Attributes: map[string]schema.Attribute{
+									"ip_address_family": schema.StringAttribute{
+										MarkdownDescription: "The type of the additional IP address (IPv4 or IPv6).",
+										Optional:            true,
+										Computed:            true,
+										Default:             stringdefault.StaticString(upcloud.IPAddressFamilyIPv4),
+										Validators: []validator.String{
+											stringvalidator.OneOf(upcloud.IPAddressFamilyIPv4, upcloud.IPAddressFamilyIPv6),
+										},
+									},
+									"ip_address": schema.StringAttribute{
+										MarkdownDescription: "The additional IP address. It has to be within the network's CIDR; assigned by UpCloud when not set.",
+										Optional:            true,
+										Computed:            true,
+										PlanModifiers: []planmodifier.String{
+											useMatchingInterfaceState{},
+										},
+									},
+									"ip_address_floating": schema.BoolAttribute{
+										MarkdownDescription: "`true` if the additional IP address is a floating IP address.",
+										Computed:            true,
+										PlanModifiers: []planmodifier.Bool{
+											useMatchingInterfaceState{},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"login": schema.ListNestedBlock{
+				MarkdownDescription: "Configure access credentials for the default user of the server. Changing any of these values replaces the server.",
+				Validators: []validator.List{
+					listvalidator.SizeAtMost(1),
+				},
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplaceIf(
+						requiresReplaceUnlessImportedList,
+						requiresReplaceUnlessImportedDescription,
+						requiresReplaceUnlessImportedDescription,
+					),
+				},
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"user": schema.StringAttribute{
+							MarkdownDescription: "Username of the default user. Defaults to the template's default user.",
+							Optional:            true,
+						},
+						"keys": schema.ListAttribute{
+							MarkdownDescription: "A list of public SSH keys allowed to log in as the default user.",
+							ElementType:         types.StringType,
+							Optional:            true,
+						},
+						"create_password": schema.BoolAttribute{
+							MarkdownDescription: "Whether a password should be generated for the default user.",
+							Optional:            true,
+							Computed:            true,
+							Default:             booldefault.StaticBool(false),
+						},
+						"password_delivery": schema.StringAttribute{
+							MarkdownDescription: "How the generated password is delivered: `none`, `email` or `sms`. With `none` the password is stored in `default_user_password`.",
+							Optional:            true,
+							Computed:            true,
+							Default:             stringdefault.StaticString(request.PasswordDeliveryNone),
+							Validators: []validator.String{
+								stringvalidator.OneOf(request.PasswordDeliveryNone, request.PasswordDeliveryEmail, request.PasswordDeliverySMS),
+							},
+						},
+					},
+				},
+			},
+			"firewall_rule": schema.ListNestedBlock{
+				MarkdownDescription: "Firewall rules of the server, evaluated in the given order. The whole rule set is replaced on change.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"direction": schema.StringAttribute{
+							MarkdownDescription: "The direction of network traffic this rule will be applied to, `in` or `out`.",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf(upcloud.FirewallRuleDirectionIn, upcloud.FirewallRuleDirectionOut),
+							},
+						},
+						"action": schema.StringAttribute{
+							MarkdownDescription: "Action to take if the rule conditions are met, `accept`, `reject` or `drop`.",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf(upcloud.FirewallRuleActionAccept, upcloud.FirewallRuleActionReject, upcloud.FirewallRuleActionDrop),
+							},
+						},
+						"family": schema.StringAttribute{
+							MarkdownDescription: "The address family of the rule, `IPv4` or `IPv6`. Required when addresses are set.",
+							Optional:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf(upcloud.IPAddressFamilyIPv4, upcloud.IPAddressFamilyIPv6),
+							},
+						},
+						"protocol": schema.StringAttribute{
+							MarkdownDescription: "The protocol this rule will be applied to, `tcp`, `udp` or `icmp`. Required when ports are set.",
+							Optional:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf(upcloud.FirewallRuleProtocolTCP, upcloud.FirewallRuleProtocolUDP, upcloud.FirewallRuleProtocolICMP),
+							},
+						},
+						"source_address_start":      firewallAddressAttribute("The source address range starts from this address."),
+						"source_address_end":        firewallAddressAttribute("The source address range ends at this address."),
+						"source_port_start":         firewallPortAttribute("The source port range starts from this port number."),
+						"source_port_end":           firewallPortAttribute("The source port range ends at this port number."),
+						"destination_address_start": firewallAddressAttribute("The destination address range starts from this address."),
+						"destination_address_end":   firewallAddressAttribute("The destination address range ends at this address."),
+						"destination_port_start":    firewallPortAttribute("The destination port range starts from this port number."),
+						"destination_port_end":      firewallPortAttribute("The destination port range ends at this port number."),
+						"comment": schema.StringAttribute{
+							MarkdownDescription: "A freeform comment for the rule.",
+							Optional:            true,
+							Validators: []validator.String{
+								stringvalidator.LengthBetween(1, 250),
+							},
+						},
+					},
+				},
+			},
+			"simple_backup": schema.ListNestedBlock{
+				MarkdownDescription: "Simple backup schedule of the server. Removing the block disables backups.",
+				Validators: []validator.List{
+					listvalidator.SizeAtMost(1),
+				},
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"plan": schema.StringAttribute{
+							MarkdownDescription: "Backup plan, `dailies`, `weeklies` or `monthlies`.",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf("dailies", "weeklies", "monthlies"),
+							},
+						},
+						"time": schema.StringAttribute{
+							MarkdownDescription: "Time of the day (UTC) at which the backup is taken, in `hhmm` format, e.g. `0100`.",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.RegexMatches(simpleBackupTimeRegexp, "must be a time in `hhmm` format"),
+							},
+						},
+					},
+				},
+			},
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+			// the OS disk cloned from the template is not part of this list
+			"storage_devices": schema.ListNestedBlock{
+				MarkdownDescription: "Additional storage devices attached to the server. Devices are matched to the current ones by `storage`, then by `title`, `address` and `size`, and finally in order; set `title` to keep new disks matched when removing or reordering devices. Attaching or detaching a device stops and starts the server.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"storage": schema.StringAttribute{
+							MarkdownDescription: "The UUID of an existing storage to attach. When not set, a new blank disk of `size` is created.",
+							Optional:            true,
+							Computed:            true,
+							PlanModifiers: []planmodifier.String{
+								useMatchingStorageDeviceState{},
+							},
+						},
+						"address": schema.StringAttribute{
+							MarkdownDescription: "The device address the storage is attached to, e.g. `virtio`, `scsi:0:0` or `ide:0:1`. `" + osStorageAddress + "` is reserved for the OS disk.",
+							Optional:            true,
+							Computed:            true,
+							Validators: []validator.String{
+								stringvalidator.RegexMatches(storageAddressRegexp, "must be a `virtio`, `scsi` or `ide` address"),
+								stringvalidator.NoneOf(osStorageAddress),
+							},
+							PlanModifiers: []planmodifier.String{
+								useMatchingStorageDeviceState{},
+							},
+						},
+						"type": schema.StringAttribute{
+							MarkdownDescription: "The device type, `disk` or `cdrom`.",
+							Optional:            true,
 							Computed:            true,
-							//Optional:            true,
-							Default: booldefault.StaticBool(false),
+							Default:             stringdefault.StaticString(upcloud.StorageTypeDisk),
+							Validators: []validator.String{
+								stringvalidator.OneOf(upcloud.StorageTypeDisk, upcloud.StorageTypeCDROM),
+							},
+						},
+						"tier": schema.StringAttribute{
+							MarkdownDescription: "The storage tier of a new disk (`maxiops`, `standard` or `hdd`). Changing it creates a new disk.",
+							Optional:            true,
+							Computed:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf(upcloud.StorageTierMaxIOPS, upcloud.StorageTierStandard, upcloud.StorageTierHDD),
+							},
+							PlanModifiers: []planmodifier.String{
+								useMatchingStorageDeviceState{},
+							},
+						},
+						"size": schema.Int64Attribute{
+							MarkdownDescription: "The size of a new disk in gigabytes. Can only be increased.",
+							Optional:            true,
+							Validators: []validator.Int64{
+								int64validator.Between(1, 4096),
+								int64validator.ExactlyOneOf(path.MatchRelative().AtParent().AtName("storage")),
+							},
+						},
+						"title": schema.StringAttribute{
+							MarkdownDescription: "The title of a new disk. Defaults to `<hostname>-disk<n>`.",
+							Optional:            true,
+							Computed:            true,
+							Validators: []validator.String{
+								stringvalidator.LengthBetween(1, 64),
+							},
+							PlanModifiers: []planmodifier.String{
+								useMatchingStorageDeviceState{},
+							},
 						},
 					},
 				},
@@ -179,18 +779,163 @@ func (r *serverResource) Configure(ctx context.Context, req resource.ConfigureRe
 		return
 	}
 
-	client, ok := req.ProviderData.(*service.Service)
+	data, ok := req.ProviderData.(*utils.ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *service.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *utils.ProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Service
+	r.apiClient = data.Client
+}
+
+func (r *serverResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var userData types.String
+	var metadata types.Bool
+	var deleteStorages types.Bool
+	var deleteBackups types.String
+	var interfaces []networkInterfaceModel
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("user_data"), &userData)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("metadata"), &metadata)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("delete_storages"), &deleteStorages)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("delete_backups"), &deleteBackups)...)
+	interfacesKnown, diags := getKnownList(ctx, req.Config, path.Root("network_interface"), &interfaces)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !userData.IsNull() && !metadata.IsUnknown() && !metadata.IsNull() && !metadata.ValueBool() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("user_data"),
+			"Invalid Attribute Combination",
+			"`user_data` requires the metadata service, it can not be used together with `metadata = false`.",
+		)
+	}
+
+	if !deleteBackups.IsNull() && !deleteStorages.IsUnknown() && !deleteStorages.IsNull() && !deleteStorages.ValueBool() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("delete_backups"),
+			"Invalid Attribute Combination",
+			"`delete_backups` only applies to deleted storages, it can not be used together with `delete_storages = false`.",
+		)
+	}
+
+	if interfacesKnown {
+		resp.Diagnostics.Append(validateNetworkInterfaces(interfaces)...)
+	}
+}
+
+func (r *serverResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to validate on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		state, diags := restartModelFromData(ctx, req.State, serverModel{})
+		resp.Diagnostics.Append(diags...)
+		plan, diags := restartModelFromData(ctx, req.Plan, state)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		resp.Diagnostics.Append(validateStorageDeviceSizes(state.StorageDevices, plan.StorageDevices)...)
+		for _, device := range planStorageDeviceChanges(state.StorageDevices, plan.StorageDevices).remove {
+			resp.Diagnostics.AddWarning(
+				"Storage Will Be Deleted",
+				fmt.Sprintf("Storage %s (%s) was created for this server and is deleted as its block was removed or replaced.", device.Storage.ValueString(), device.Title.ValueString()),
+			)
+		}
+
+		if attributes := restartRequiredAttributes(state, plan); len(attributes) > 0 {
+			if restartAllowed(plan.AllowRestart) {
+				resp.Diagnostics.AddWarning("Server Restart Required", restartRequiredMessage(attributes)+" The server will be restarted during apply.")
+			} else {
+				resp.Diagnostics.AddError("Server Restart Not Allowed", restartRequiredMessage(attributes)+" Set `allow_restart = true` to allow it.")
+			}
+		}
+	} else {
+		var interfaces []networkInterfaceModel
+		known, diags := getKnownList(ctx, req.Config, path.Root("network_interface"), &interfaces)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if known {
+			resp.Diagnostics.Append(validateCreatedInterfaceIndexes(interfaces)...)
+		}
+	}
+
+	// the remaining checks need the API
+	if r.client == nil {
+		return
+	}
+
+	var configTimezone, stateTimezone types.String
+	var configZone, stateZone, configZoneType, stateZoneType types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("timezone"), &configTimezone)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("zone"), &configZone)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("zone_type"), &configZoneType)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("timezone"), &stateTimezone)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("zone"), &stateZone)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("zone_type"), &stateZoneType)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !configZone.IsUnknown() && !configZoneType.IsUnknown() && (!configZone.Equal(stateZone) || !configZoneType.Equal(stateZoneType)) {
+		if err := validateZone(ctx, r.client, configZone.ValueString(), configZoneType.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("zone"), "Zone Error", fmt.Sprintf("Unable to find provided zone, got error: %s", err))
+		}
+	}
+
+	// interfaces which are not known yet are checked on the next plan
+	var configInterfaces, stateInterfaces []networkInterfaceModel
+	_, diags := getKnownList(ctx, req.Config, path.Root("network_interface"), &configInterfaces)
+	resp.Diagnostics.Append(diags...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("network_interface"), &stateInterfaces)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	matches := matchNetworkInterfaces(stateInterfaces, configInterfaces)
+	for i, iface := range configInterfaces {
+		if iface.Network.IsNull() || iface.Network.IsUnknown() {
+			continue
+		}
+		var stateInterface networkInterfaceModel
+		if matches[i] >= 0 {
+			stateInterface = stateInterfaces[matches[i]]
+		}
+
+		addresses := changedInterfaceAddresses(path.Root("network_interface").AtListIndex(i), stateInterface, iface)
+		for _, a := range addresses {
+			if a.address.IsNull() || a.address.IsUnknown() {
+				continue
+			}
+			if err := validateInterfaceAddress(ctx, r.client, iface.Network.ValueString(), a.address.ValueString()); err != nil {
+				resp.Diagnostics.AddAttributeError(a.path, "IP Address Error", fmt.Sprintf("Unable to use provided IP address, got error: %s", err))
+			}
+		}
+	}
+
+	if !configTimezone.IsNull() && !configTimezone.IsUnknown() && !configTimezone.Equal(stateTimezone) {
+		if err := validateTimezone(ctx, r.client, configTimezone.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("timezone"), "Timezone Error", fmt.Sprintf("Unable to find provided timezone, got error: %s", err))
+		}
+	}
 }
 
 func (r *serverResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -201,39 +946,103 @@ func (r *serverResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
-	if err := validateZone(ctx, r.client, data.Zone.ValueString()); err != nil {
-		resp.Diagnostics.AddError("Zone Error", fmt.Sprintf("Unable to find provided zone, got error: %s", err))
+	createTimeout, diags := data.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+	defer reportTimeout(ctx, r.client, &resp.Diagnostics, "create", createTimeout, &data.ID)
+
 	networking, diags := buildNetworkInterfaceRequestForServer(data.NetworkInterface)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	storageDevices, diags := buildStorageDevicesForServer(data.Hostname.ValueString(), data.StorageDevices)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	labels, diags := buildLabelsForServer(ctx, data.Labels)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tags, diags := tagsFromModel(ctx, data.Tags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	serverReq := &request.CreateServerRequest{
 		Hostname:   data.Hostname.ValueString(),
 		Zone:       data.Zone.ValueString(),
 		Networking: networking,
-		StorageDevices: []request.CreateServerStorageDevice{{
+		StorageDevices: append(request.CreateServerStorageDeviceSlice{{
 			Action:  "clone",
+			Address: osStorageAddress,
 			Size:    20,
 			Storage: "01000000-0000-4000-8000-000030240200",
 			Tier:    "maxiops",
 			Title:   "Ubuntu-24-04-LTS",
-		}},
-		Title:    fmt.Sprintf("%s %s", data.Hostname.ValueString(), "(terraform resource)"),
-		Metadata: upcloud.FromBool(true),
+		}}, storageDevices...),
+		Title:        data.Title.ValueString(),
+		Metadata:     upcloud.FromBool(data.Metadata.ValueBool()),
+		UserData:     data.UserData.ValueString(),
+		Labels:       labels,
+		Firewall:     firewallEnabled(data.Firewall),
+		SimpleBackup: buildSimpleBackupForServer(data.SimpleBackup),
+		BootOrder:    data.BootOrder.ValueString(),
+		NICModel:     data.NICModel.ValueString(),
+		VideoModel:   data.VideoModel.ValueString(),
+		TimeZone:     data.Timezone.ValueString(),
+		ServerGroup:  data.ServerGroup.ValueString(),
+		Host:         int(data.Host.ValueInt64()),
+		AvoidHost:    int(data.AvoidHost.ValueInt64()),
+
+		RemoteAccessEnabled:  upcloud.FromBool(data.RemoteAccessEnabled.ValueBool()),
+		RemoteAccessType:     data.RemoteAccessType.ValueString(),
+		RemoteAccessPassword: data.RemoteAccessPassword.ValueString(),
 	}
+	serverReq.LoginUser, serverReq.PasswordDelivery = buildLoginUserForServer(data.Login)
 
-	details, err := r.client.CreateServer(ctx, serverReq)
+	var details *upcloud.ServerDetails
+	var err error
+	data.DefaultUserPassword = types.StringNull()
+	if isPasswordReturned(data.Login) {
+		var password string
+		details, password, err = createServerWithPassword(context.WithoutCancel(ctx), r.apiClient, serverReq)
+		if err == nil {
+			data.DefaultUserPassword = types.StringValue(password)
+		}
+	} else {
+		details, err = r.client.CreateServer(context.WithoutCancel(ctx), serverReq)
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to create server, got error: %s", err))
 		return
 	}
+	data.ID = types.StringValue(details.UUID)
+
+	// track the server right away, so that a failure below leaves it tainted instead of orphaned
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), data.ID)...)
+	// Delete tells the disks created here from the attached storages by size, so they have to be tracked as well
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("storage_devices"), storageDevicesFromServerDetails(data.StorageDevices, details.StorageDevices))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("delete_storages"), data.DeleteStorages)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("delete_backups"), data.DeleteBackups)...)
+	defer func() {
+		if resp.Diagnostics.HasError() && data.RollbackOnFailure.ValueBool() {
+			rollbackCreatedServer(ctx, r.client, data, &resp.State, &resp.Diagnostics)
+		}
+	}()
 
-	_, err = r.client.WaitForServerState(ctx, &request.WaitForServerStateRequest{
+	details, err = r.client.WaitForServerState(ctx, &request.WaitForServerStateRequest{
 		UUID:         details.UUID,
 		DesiredState: upcloud.ServerStateStarted,
 	})
@@ -242,7 +1051,41 @@ func (r *serverResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
+	if data.PowerState.ValueString() == upcloud.ServerStateStopped {
+		if err := utils.VerifyServerStopped(ctx, buildStopServerRequest(data), r.client); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to stop server, got error: %s", err))
+			return
+		}
+	}
+
+	// tags can not be set with the create request
+	if len(tags) > 0 {
+		if err := reconfigureServerTags(ctx, r.client, details.UUID, nil, tags); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to tag server, got error: %s", err))
+			return
+		}
+	}
+
+	if len(data.FirewallRule) > 0 {
+		if err := replaceServerFirewallRules(ctx, r.client, details.UUID, data.FirewallRule); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to create firewall rules, got error: %s", err))
+			return
+		}
+	}
+
+	details, err = r.client.GetServerDetails(ctx, &request.GetServerDetailsRequest{
+		UUID: details.UUID,
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to get server, got error: %s", err))
+		return
+	}
+
 	resp.Diagnostics.Append(setServerValues(&data, details)...)
+	if err := readServerFirewallRules(ctx, r.client, &data); err != nil {
+		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read firewall rules, got error: %s", err))
+		return
+	}
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -259,12 +1102,33 @@ func (r *serverResource) Read(ctx context.Context, req resource.ReadRequest, res
 	}
 
 	details, err := r.client.GetServerDetails(ctx, getRequest)
+	if utils.IsServerNotFound(err) {
+		// deleted outside of Terraform, drop it from state so that it gets recreated
+		tflog.Warn(ctx, "server not found, removing it from state", map[string]interface{}{"uuid": data.ID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read server details, got error: %s", err))
 		return
 	}
 
+	imported, diags := wasImported(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// storage devices are only missing from state on the first read after an import
+	adopt := imported && data.StorageDevices == nil
+
 	resp.Diagnostics.Append(setServerValues(&data, details)...)
+	if adopt {
+		adoptStorageDevices(data.StorageDevices, details.StorageDevices)
+	}
+	if err := readServerFirewallRules(ctx, r.client, &data); err != nil {
+		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read firewall rules, got error: %s", err))
+		return
+	}
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -284,53 +1148,136 @@ func (r *serverResource) Update(ctx context.Context, req resource.UpdateRequest,
 		return
 	}
 
+	updateTimeout, diags := dataPlan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+	defer reportTimeout(ctx, r.client, &resp.Diagnostics, "update", updateTimeout, &dataPlan.ID)
+
 	// Verify if network is updated
-	isNetworkReconfigured := false
-	if len(dataState.NetworkInterface) != len(dataPlan.NetworkInterface) {
-		isNetworkReconfigured = true
-	} else {
-		for i := range dataState.NetworkInterface {
-			if dataState.NetworkInterface[i].IpAddressFamily.ValueString() != dataPlan.NetworkInterface[i].IpAddressFamily.ValueString() {
-				isNetworkReconfigured = true
-				break
-			}
+	isNetworkReconfigured := networkInterfacesChanged(dataState.NetworkInterface, dataPlan.NetworkInterface)
+
+	// Verify if storage devices are updated
+	storageChanges := planStorageDeviceChanges(dataState.StorageDevices, dataPlan.StorageDevices)
+	isStorageReconfigured := !storageChanges.isEmpty()
+
+	// Verify if hardware requiring a restart is updated
+	isHardwareReconfigured := len(hardwareChanges(dataState, dataPlan)) > 0
+
+	// Network, storage and hardware changes - server needs to be stopped
+	if isNetworkReconfigured || isStorageReconfigured || isHardwareReconfigured {
+		if attributes := restartRequiredAttributes(dataState, dataPlan); len(attributes) > 0 && !restartAllowed(dataPlan.AllowRestart) {
+			resp.Diagnostics.AddError("Server Restart Not Allowed", restartRequiredMessage(attributes)+" Set `allow_restart = true` to allow it.")
+			return
+		}
+		if err := utils.VerifyServerStopped(ctx, buildStopServerRequest(dataPlan), r.client); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to stop server, got error: %s", err))
+			return
 		}
 	}
 
-	// Reconfigure network - server needs to be stopped
+	// Reconfigure network
 	if isNetworkReconfigured {
-		net, err := buildNetworkInterfaceRequestFromServerModel(&dataPlan)
-		if err != nil {
-			resp.Diagnostics.AddError("Unable to build network interface", fmt.Sprintf("got error: %s", err))
+		if err := reconfigureServerNetworkInterfaces(ctx, r.client, dataPlan); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to refresh interfaces, got error: %s", err))
 			return
 		}
+	}
 
-		if err := utils.VerifyServerStopped(ctx, request.StopServerRequest{UUID: dataPlan.ID.ValueString()}, r.client); err != nil {
-			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to stop server, got error: %s", err))
+	// Reconfigure storage devices
+	if isStorageReconfigured {
+		if err := reconfigureServerStorageDevices(ctx, r.client, &dataPlan, storageChanges); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to refresh storage devices, got error: %s", err))
 			return
 		}
+	}
 
-		if err := reconfigureServerNetworkInterfaces(ctx, r.client, dataPlan, net); err != nil {
-			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to refresh interfaces, got error: %s", err))
+	modifyReq := &request.ModifyServerRequest{
+		UUID:         dataPlan.ID.ValueString(),
+		Hostname:     dataPlan.Hostname.ValueString(),
+		Title:        dataPlan.Title.ValueString(),
+		Firewall:     firewallEnabled(dataPlan.Firewall),
+		SimpleBackup: buildSimpleBackupForServer(dataPlan.SimpleBackup),
+		BootOrder:    dataPlan.BootOrder.ValueString(),
+		NICModel:     dataPlan.NICModel.ValueString(),
+		VideoModel:   dataPlan.VideoModel.ValueString(),
+		TimeZone:     dataPlan.Timezone.ValueString(),
+		Metadata:     upcloud.FromBool(dataPlan.Metadata.ValueBool()),
+
+		RemoteAccessEnabled:  upcloud.FromBool(dataPlan.RemoteAccessEnabled.ValueBool()),
+		RemoteAccessType:     dataPlan.RemoteAccessType.ValueString(),
+		RemoteAccessPassword: dataPlan.RemoteAccessPassword.ValueString(),
+	}
+
+	if !dataPlan.Labels.Equal(dataState.Labels) {
+		labels, diags := buildLabelsForServer(ctx, dataPlan.Labels)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
 			return
 		}
+		// an empty slice removes all labels
+		if labels == nil {
+			labels = &upcloud.LabelSlice{}
+		}
+		modifyReq.Labels = labels
 	}
 
-	_, err := r.client.ModifyServer(ctx, &request.ModifyServerRequest{
-		UUID:     dataPlan.ID.ValueString(),
-		Hostname: dataPlan.Hostname.ValueString(),
-	})
+	_, err := r.client.ModifyServer(ctx, modifyReq)
 	if err != nil {
 		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to update server, got error: %s", err))
 		return
 	}
 
-	/// After network reconfiguration - server needs to be started
-	if isNetworkReconfigured {
-		if err := utils.VerifyServerStarted(ctx, request.StartServerRequest{UUID: dataPlan.ID.ValueString()}, r.client); err != nil {
+	if !dataPlan.ServerGroup.Equal(dataState.ServerGroup) {
+		if err := reconfigureServerGroup(ctx, r.client, dataPlan.ID.ValueString(), dataState.ServerGroup, dataPlan.ServerGroup); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to update server group, got error: %s", err))
+			return
+		}
+	}
+
+	if !firewallRulesEquals(dataState.FirewallRule, dataPlan.FirewallRule) {
+		if err := replaceServerFirewallRules(ctx, r.client, dataPlan.ID.ValueString(), dataPlan.FirewallRule); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to update firewall rules, got error: %s", err))
+			return
+		}
+	}
+
+	if !dataPlan.Tags.Equal(dataState.Tags) {
+		currentTags, diags := tagsFromModel(ctx, dataState.Tags)
+		resp.Diagnostics.Append(diags...)
+		plannedTags, diags := tagsFromModel(ctx, dataPlan.Tags)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if err := reconfigureServerTags(ctx, r.client, dataPlan.ID.ValueString(), currentTags, plannedTags); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to update server tags, got error: %s", err))
+			return
+		}
+	}
+
+	/// After network, storage or hardware reconfiguration - server is brought to the planned power state
+	switch dataPlan.PowerState.ValueString() {
+	case upcloud.ServerStateStarted:
+		startReq := request.StartServerRequest{
+			UUID:      dataPlan.ID.ValueString(),
+			Host:      int(dataPlan.Host.ValueInt64()),
+			AvoidHost: int(dataPlan.AvoidHost.ValueInt64()),
+		}
+		if err := utils.VerifyServerStarted(ctx, startReq, r.client); err != nil {
 			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to start server, got error: %s", err))
 			return
 		}
+	case upcloud.ServerStateStopped:
+		if err := utils.VerifyServerStopped(ctx, buildStopServerRequest(dataPlan), r.client); err != nil {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to stop server, got error: %s", err))
+			return
+		}
 	}
 
 	details, err := r.client.GetServerDetails(ctx, &request.GetServerDetailsRequest{
@@ -342,7 +1289,13 @@ func (r *serverResource) Update(ctx context.Context, req resource.UpdateRequest,
 	}
 
 	resp.Diagnostics.Append(setServerValues(&dataPlan, details)...)
+	if err := readServerFirewallRules(ctx, r.client, &dataPlan); err != nil {
+		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read firewall rules, got error: %s", err))
+		return
+	}
 	resp.Diagnostics.Append(resp.State.Set(ctx, &dataPlan)...)
+	// values configured for an imported server are known from now on
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedPrivateKey, nil)...)
 }
 
 func (r *serverResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -353,26 +1306,94 @@ func (r *serverResource) Delete(ctx context.Context, req resource.DeleteRequest,
 		return
 	}
 
-	if err := utils.VerifyServerStopped(ctx, request.StopServerRequest{UUID: data.ID.ValueString()}, r.client); err != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to stop server, got error: %s", err))
+	deleteTimeout, diags := data.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	deleteServerRequest := &request.DeleteServerAndStoragesRequest{
-		UUID: data.ID.ValueString(),
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+	defer reportTimeout(ctx, r.client, &resp.Diagnostics, "delete", deleteTimeout, &data.ID)
+
+	err := utils.VerifyServerStopped(ctx, buildStopServerRequest(data), r.client)
+	if utils.IsServerNotFound(err) {
+		// already deleted outside of Terraform
+		return
 	}
+	if err != nil {
+		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to stop server, got error: %s", err))
+		return
+	}
+
+	// state written before delete_storages existed keeps the previous behaviour
+	if data.DeleteStorages.IsNull() || data.DeleteStorages.ValueBool() {
+		imported, diags := wasImported(ctx, req.Private)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// all storages of a server which was imported and not updated since belong to it
+		if !imported {
+			if err := detachAttachedStorageDevices(ctx, r.client, data); err != nil {
+				resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to detach storage devices, got error: %s", err))
+				return
+			}
+		}
 
-	if err := r.client.DeleteServerAndStorages(ctx, deleteServerRequest); err != nil {
+		deleteServerRequest := &request.DeleteServerAndStoragesRequest{
+			UUID:    data.ID.ValueString(),
+			Backups: request.DeleteStorageBackupsMode(data.DeleteBackups.ValueString()),
+		}
+
+		if err := r.client.DeleteServerAndStorages(ctx, deleteServerRequest); err != nil && !utils.IsServerNotFound(err) {
+			resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to delete server, got error: %s", err))
+			return
+		}
+	} else if err := deleteServerKeepingStorages(ctx, r.client, data.ID.ValueString()); err != nil && !utils.IsServerNotFound(err) {
 		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to delete server, got error: %s", err))
 		return
 	}
-
-	resp.Diagnostics.Append(setServerValues(&data, nil)...)
-	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *serverResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	uuid, err := resolveImportID(ctx, r.client, req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Import Error", fmt.Sprintf("Unable to find server to import, got error: %s", err))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), uuid)...)
+	resp.Diagnostics.Append(setConfigOnlyDefaults(ctx, &resp.State)...)
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedPrivateKey, []byte("true"))...)
+}
+
+func buildStopServerRequest(data serverModel) request.StopServerRequest {
+	return request.StopServerRequest{
+		UUID:     data.ID.ValueString(),
+		StopType: data.StopType.ValueString(),
+		Timeout:  time.Duration(data.ShutdownTimeout.ValueInt64()) * time.Second,
+	}
+}
+
+func reconfigureServerGroup(ctx context.Context, svc *service.Service, uuid string, current, planned types.String) error {
+	if !current.IsNull() {
+		if err := svc.RemoveServerFromServerGroup(ctx, &request.RemoveServerFromServerGroupRequest{
+			ServerUUID: uuid,
+			UUID:       current.ValueString(),
+		}); err != nil {
+			return fmt.Errorf("unable to remove server from group %s; %w", current.ValueString(), err)
+		}
+	}
+	if !planned.IsNull() {
+		if err := svc.AddServerToServerGroup(ctx, &request.AddServerToServerGroupRequest{
+			ServerUUID: uuid,
+			UUID:       planned.ValueString(),
+		}); err != nil {
+			return fmt.Errorf("unable to add server to group %s; %w", planned.ValueString(), err)
+		}
+	}
+	return nil
 }
 
 func buildNetworkInterfaceRequestForServer(dataNetworkInterfaces []networkInterfaceModel) (*request.CreateServerNetworking, diag.Diagnostics) {
@@ -383,13 +1404,26 @@ func buildNetworkInterfaceRequestForServer(dataNetworkInterfaces []networkInterf
 	interfacesResponse := make(request.CreateServerInterfaceSlice, 0, len(dataNetworkInterfaces))
 
 	for _, inter := range dataNetworkInterfaces {
-		interfacesResponse = append(interfacesResponse, request.CreateServerInterface{
-			IPAddresses: []request.CreateServerIPAddress{
-				{
-					Family: inter.IpAddressFamily.ValueString(),
-				},
+		var address, network string
+		if inter.Type.ValueString() == upcloud.NetworkTypePrivate {
+			address = knownStringValue(inter.IpAddress)
+			network = inter.Network.ValueString()
+		}
+		ipAddresses := []request.CreateServerIPAddress{
+			{
+				Family:  inter.IpAddressFamily.ValueString(),
+				Address: address,
 			},
-			Network:           inter.Network.ValueString(),
+		}
+		for _, ip := range inter.AdditionalIPAddress {
+			ipAddresses = append(ipAddresses, request.CreateServerIPAddress{
+				Family:  ip.IpAddressFamily.ValueString(),
+				Address: knownStringValue(ip.IpAddress),
+			})
+		}
+		interfacesResponse = append(interfacesResponse, request.CreateServerInterface{
+			IPAddresses:       ipAddresses,
+			Network:           network,
 			Type:              inter.Type.ValueString(),
 			SourceIPFiltering: upcloud.FromBool(inter.SourceIpFiltering.ValueBool()),
 			Bootable:          upcloud.FromBool(inter.Bootable.ValueBool()),
@@ -411,25 +1445,44 @@ func setServerValues(data *serverModel, details *upcloud.ServerDetails) diag.Dia
 	data.ID = types.StringValue(details.UUID)
 	data.Hostname = types.StringValue(details.Hostname)
 	data.Zone = types.StringValue(details.Zone)
+	data.Title = types.StringValue(details.Title)
+	data.Plan = types.StringValue(details.Plan)
+	data.CPU = types.Int64Value(int64(details.CoreNumber))
+	data.Mem = types.Int64Value(int64(details.MemoryAmount))
+	data.CurrentHost = types.Int64Value(int64(details.Host))
+	data.Firewall = types.BoolValue(details.Firewall == "on")
+	data.SimpleBackup = simpleBackupFromServerDetails(details.SimpleBackup)
+	data.BootOrder = types.StringValue(details.BootOrder)
+	data.NICModel = types.StringValue(details.NICModel)
+	data.VideoModel = types.StringValue(details.VideoModel)
+	data.Timezone = types.StringValue(details.Timezone)
 
-	data.NetworkInterface = make([]networkInterfaceModel, len(details.Networking.Interfaces))
-	for i, iface := range details.Networking.Interfaces {
-		networkInterface := networkInterfaceModel{
-			MacAddress:        types.StringValue(iface.MAC),
-			Type:              types.StringValue(iface.Type),
-			SourceIpFiltering: types.BoolValue(iface.SourceIPFiltering.Bool()),
-			Bootable:          types.BoolValue(iface.Bootable.Bool()),
-			Network:           types.StringValue(iface.Network),
-		}
+	data.RemoteAccessEnabled = types.BoolValue(details.RemoteAccessEnabled.Bool())
+	data.RemoteAccessType = types.StringValue(details.RemoteAccessType)
+	data.RemoteAccessPassword = types.StringValue(details.RemoteAccessPassword)
+	data.RemoteAccessHost = types.StringValue(details.RemoteAccessHost)
+	data.RemoteAccessPort = types.Int64Value(int64(details.RemoteAccessPort))
+	if details.ServerGroup != "" {
+		data.ServerGroup = types.StringValue(details.ServerGroup)
+	} else {
+		data.ServerGroup = types.StringNull()
+	}
+	// transitional states (e.g. maintenance) are not reported as drift
+	if details.State == upcloud.ServerStateStarted || details.State == upcloud.ServerStateStopped {
+		data.PowerState = types.StringValue(details.State)
+	}
+	data.Metadata = types.BoolValue(details.Metadata.Bool())
 
-		for _, ip := range iface.IPAddresses {
-			networkInterface.IpAddress = types.StringValue(ip.Address)
-			networkInterface.IpAddressFamily = types.StringValue(ip.Family)
-			networkInterface.IpAddressFloating = types.BoolValue(ip.Floating.Bool())
-		}
+	labels, diags := labelsFromServerDetails(data.Labels, details.Labels)
+	diagsResp.Append(diags...)
+	data.Labels = labels
 
-		data.NetworkInterface[i] = networkInterface
-	}
+	tags, diags := tagsFromServerDetails(data.Tags, details.Tags)
+	diagsResp.Append(diags...)
+	data.Tags = tags
+
+	data.NetworkInterface = networkInterfacesFromServerDetails(data.NetworkInterface, details.Networking.Interfaces)
+	data.StorageDevices = storageDevicesFromServerDetails(data.StorageDevices, details.StorageDevices)
 
 	return diagsResp
 }
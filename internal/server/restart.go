@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+type attributeGetter interface {
+	GetAttribute(ctx context.Context, p path.Path, target interface{}) diag.Diagnostics
+}
+
+// getKnownList reads a list block into target. It reports false without an error when the list or
+// a list nested in it is unknown (e.g. a dynamic block over a computed value), as those can not be
+// read into a model.
+func getKnownList(ctx context.Context, data attributeGetter, p path.Path, target interface{}) (bool, diag.Diagnostics) {
+	var list types.List
+	diags := data.GetAttribute(ctx, p, &list)
+	if diags.HasError() || !listFullyKnown(list) {
+		return false, diags
+	}
+	diags.Append(list.ElementsAs(ctx, target, false)...)
+	return !diags.HasError(), diags
+}
+
+func listFullyKnown(list types.List) bool {
+	if list.IsUnknown() {
+		return false
+	}
+	for _, element := range list.Elements() {
+		object, ok := element.(types.Object)
+		if !ok {
+			continue
+		}
+		if object.IsUnknown() {
+			return false
+		}
+		for _, value := range object.Attributes() {
+			if nested, ok := value.(types.List); ok && !listFullyKnown(nested) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// restartModelFromData reads only the attributes needed to tell whether a change requires a restart,
+// as the rest of a plan might contain unknown values which can not be read into serverModel.
+// Blocks which are not known yet keep the prior values; they are checked again during apply.
+func restartModelFromData(ctx context.Context, data attributeGetter, prior serverModel) (serverModel, diag.Diagnostics) {
+	var m serverModel
+	var diags diag.Diagnostics
+	known, d := getKnownList(ctx, data, path.Root("network_interface"), &m.NetworkInterface)
+	diags.Append(d...)
+	if !known {
+		m.NetworkInterface = prior.NetworkInterface
+	}
+	known, d = getKnownList(ctx, data, path.Root("storage_devices"), &m.StorageDevices)
+	diags.Append(d...)
+	if !known {
+		m.StorageDevices = prior.StorageDevices
+	}
+	diags.Append(data.GetAttribute(ctx, path.Root("nic_model"), &m.NICModel)...)
+	diags.Append(data.GetAttribute(ctx, path.Root("video_model"), &m.VideoModel)...)
+	diags.Append(data.GetAttribute(ctx, path.Root("timezone"), &m.Timezone)...)
+	diags.Append(data.GetAttribute(ctx, path.Root("power_state"), &m.PowerState)...)
+	diags.Append(data.GetAttribute(ctx, path.Root("allow_restart"), &m.AllowRestart)...)
+	return m, diags
+}
+
+// networkInterfacesChanged reports whether any interface has to be created, deleted or modified;
+// interfaces are matched by identity, so reordering alone is not a change.
+func networkInterfacesChanged(state, plan []networkInterfaceModel) bool {
+	kept := 0
+	for i, j := range matchNetworkInterfaces(state, plan) {
+		if j < 0 {
+			return true
+		}
+		kept++
+		if !knownValueEquals(state[j].IpAddress, plan[i].IpAddress) ||
+			state[j].SourceIpFiltering.ValueBool() != plan[i].SourceIpFiltering.ValueBool() ||
+			state[j].Bootable.ValueBool() != plan[i].Bootable.ValueBool() ||
+			additionalIPAddressesChanged(state[j].AdditionalIPAddress, plan[i].AdditionalIPAddress) {
+			return true
+		}
+	}
+	return kept != len(state)
+}
+
+func additionalIPAddressesChanged(state, plan []additionalIPAddressModel) bool {
+	if len(state) != len(plan) {
+		return true
+	}
+	for i := range state {
+		if state[i].IpAddressFamily.ValueString() != plan[i].IpAddressFamily.ValueString() ||
+			!knownValueEquals(state[i].IpAddress, plan[i].IpAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+// knownValueEquals reports whether the planned value equals the current one; unknown values are
+// assigned by UpCloud and do not count as a change on their own.
+func knownValueEquals(current, planned types.String) bool {
+	return planned.IsUnknown() || current.ValueString() == planned.ValueString()
+}
+
+// hardwareChanges returns the changed hardware attributes which can only be modified while the server is stopped.
+func hardwareChanges(state, plan serverModel) []string {
+	changes := make([]string, 0)
+	for _, a := range []struct {
+		name        string
+		state, plan attr.Value
+	}{
+		{"nic_model", state.NICModel, plan.NICModel},
+		{"video_model", state.VideoModel, plan.VideoModel},
+		{"timezone", state.Timezone, plan.Timezone},
+	} {
+		if !a.plan.Equal(a.state) {
+			changes = append(changes, a.name)
+		}
+	}
+	return changes
+}
+
+// restartRequiredAttributes returns the attributes whose planned changes require the server to be stopped.
+func restartRequiredAttributes(state, plan serverModel) []string {
+	// a server which is stopped after apply is never restarted
+	if plan.PowerState.ValueString() == upcloud.ServerStateStopped {
+		return nil
+	}
+
+	attributes := make([]string, 0)
+	if networkInterfacesChanged(state.NetworkInterface, plan.NetworkInterface) {
+		attributes = append(attributes, "network_interface")
+	}
+	if !planStorageDeviceChanges(state.StorageDevices, plan.StorageDevices).isEmpty() {
+		attributes = append(attributes, "storage_devices")
+	}
+	return append(attributes, hardwareChanges(state, plan)...)
+}
+
+// restartAllowed treats a missing value (state written before allow_restart existed) as allowed.
+func restartAllowed(allowRestart types.Bool) bool {
+	return allowRestart.IsNull() || allowRestart.IsUnknown() || allowRestart.ValueBool()
+}
+
+func restartRequiredMessage(attributes []string) string {
+	quoted := make([]string, 0, len(attributes))
+	for _, a := range attributes {
+		quoted = append(quoted, fmt.Sprintf("`%s`", a))
+	}
+	return fmt.Sprintf("Changing %s requires the server to be stopped.", strings.Join(quoted, ", "))
+}
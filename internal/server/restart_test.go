@@ -0,0 +1,160 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartRequiredAttributes(t *testing.T) {
+	publicIPv4 := networkInterfaceModel{
+		IpAddressFamily: types.StringValue(upcloud.IPAddressFamilyIPv4),
+		Type:            types.StringValue(upcloud.NetworkTypePublic),
+		Network:         types.StringValue("public"),
+	}
+	publicIPv6 := networkInterfaceModel{
+		IpAddressFamily: types.StringValue(upcloud.IPAddressFamilyIPv6),
+		Type:            types.StringValue(upcloud.NetworkTypePublic),
+		Network:         types.StringUnknown(),
+	}
+	base := serverModel{
+		PowerState:       types.StringValue(upcloud.ServerStateStarted),
+		NICModel:         types.StringValue("virtio"),
+		VideoModel:       types.StringValue("vga"),
+		Timezone:         types.StringValue("UTC"),
+		NetworkInterface: []networkInterfaceModel{publicIPv4},
+	}
+
+	testCases := []struct {
+		name       string
+		state      func(m serverModel) serverModel
+		plan       func(m serverModel) serverModel
+		attributes []string
+	}{
+		{
+			name:       "no changes",
+			attributes: []string{},
+		},
+		{
+			name: "interface added",
+			plan: func(m serverModel) serverModel {
+				m.NetworkInterface = []networkInterfaceModel{publicIPv4, publicIPv6}
+				return m
+			},
+			attributes: []string{"network_interface"},
+		},
+		{
+			name: "interfaces reordered",
+			state: func(m serverModel) serverModel {
+				m.NetworkInterface = []networkInterfaceModel{publicIPv4, publicIPv6}
+				return m
+			},
+			plan: func(m serverModel) serverModel {
+				m.NetworkInterface = []networkInterfaceModel{publicIPv6, publicIPv4}
+				return m
+			},
+			attributes: []string{},
+		},
+		{
+			name: "interface family changed",
+			plan: func(m serverModel) serverModel {
+				m.NetworkInterface = []networkInterfaceModel{publicIPv6}
+				return m
+			},
+			attributes: []string{"network_interface"},
+		},
+		{
+			name: "storage and timezone changed",
+			plan: func(m serverModel) serverModel {
+				m.StorageDevices = []storageDeviceModel{{Storage: types.StringUnknown(), Size: types.Int64Value(10)}}
+				m.Timezone = types.StringValue("Europe/Helsinki")
+				return m
+			},
+			attributes: []string{"storage_devices", "timezone"},
+		},
+		{
+			name: "stopped server stays stopped",
+			state: func(m serverModel) serverModel {
+				m.PowerState = types.StringValue(upcloud.ServerStateStopped)
+				return m
+			},
+			plan: func(m serverModel) serverModel {
+				m.PowerState = types.StringValue(upcloud.ServerStateStopped)
+				m.NICModel = types.StringValue("e1000")
+				return m
+			},
+		},
+		{
+			name: "server stopped with hardware change",
+			plan: func(m serverModel) serverModel {
+				m.PowerState = types.StringValue(upcloud.ServerStateStopped)
+				m.NICModel = types.StringValue("e1000")
+				m.StorageDevices = []storageDeviceModel{{Storage: types.StringUnknown(), Size: types.Int64Value(10)}}
+				return m
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			state, plan := base, base
+			if testCase.state != nil {
+				state = testCase.state(state)
+			}
+			if testCase.plan != nil {
+				plan = testCase.plan(plan)
+			}
+			assert.Equal(t, testCase.attributes, restartRequiredAttributes(state, plan))
+		})
+	}
+}
+
+func TestListFullyKnown(t *testing.T) {
+	addressType := types.ObjectType{AttrTypes: map[string]attr.Type{"ip_address": types.StringType}}
+	interfaceType := types.ObjectType{AttrTypes: map[string]attr.Type{
+		"type":                  types.StringType,
+		"additional_ip_address": types.ListType{ElemType: addressType},
+	}}
+	iface := func(addresses types.List) attr.Value {
+		return types.ObjectValueMust(interfaceType.AttrTypes, map[string]attr.Value{
+			"type":                  types.StringUnknown(),
+			"additional_ip_address": addresses,
+		})
+	}
+
+	testCases := []struct {
+		name  string
+		list  types.List
+		known bool
+	}{
+		{
+			name:  "null list",
+			list:  types.ListNull(interfaceType),
+			known: true,
+		},
+		{
+			name:  "unknown attribute",
+			list:  types.ListValueMust(interfaceType, []attr.Value{iface(types.ListValueMust(addressType, nil))}),
+			known: true,
+		},
+		{
+			name: "unknown list",
+			list: types.ListUnknown(interfaceType),
+		},
+		{
+			name: "unknown element",
+			list: types.ListValueMust(interfaceType, []attr.Value{types.ObjectUnknown(interfaceType.AttrTypes)}),
+		},
+		{
+			name: "unknown nested list",
+			list: types.ListValueMust(interfaceType, []attr.Value{iface(types.ListUnknown(addressType))}),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.known, listFullyKnown(testCase.list))
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/upcloud-terraform-provider-server/internal/utils"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// time given to delete a server which failed to be created
+const rollbackTimeout = 10 * time.Minute
+
+// rollbackCreatedServer deletes a server which failed to be fully created and removes it from state.
+// If the rollback fails, the server is left in state to be replaced on the next apply.
+func rollbackCreatedServer(ctx context.Context, svc *service.Service, data serverModel, state *tfsdk.State, diags *diag.Diagnostics) {
+	// the create context might have been cancelled or timed out already
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	tflog.Info(ctx, "rolling back server", map[string]interface{}{"uuid": data.ID.ValueString()})
+	if err := deleteCreatedServer(ctx, svc, data); err != nil {
+		diags.AddError("Rollback Error", fmt.Sprintf("Unable to delete server %s after failed create, it is kept as tainted, got error: %s", data.ID.ValueString(), err))
+		return
+	}
+	state.RemoveResource(ctx)
+}
+
+func deleteCreatedServer(ctx context.Context, svc *service.Service, data serverModel) error {
+	uuid := data.ID.ValueString()
+	err := utils.VerifyServerStopped(ctx, request.StopServerRequest{
+		UUID:     uuid,
+		StopType: upcloud.StopTypeHard,
+	}, svc)
+	if utils.IsServerNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	details, err := svc.GetServerDetails(ctx, &request.GetServerDetailsRequest{
+		UUID: uuid,
+	})
+	if err != nil {
+		return err
+	}
+
+	// existing storages attached in the create request must survive the rollback
+	attached := make(map[string]bool)
+	for _, device := range data.StorageDevices {
+		if !device.Storage.IsNull() && !device.Storage.IsUnknown() {
+			attached[device.Storage.ValueString()] = true
+		}
+	}
+	for _, device := range details.StorageDevices {
+		if !attached[device.UUID] {
+			continue
+		}
+		if _, err := svc.DetachStorage(ctx, &request.DetachStorageRequest{
+			ServerUUID: uuid,
+			Address:    device.Address,
+		}); err != nil {
+			return fmt.Errorf("unable to detach storage %s; %w", device.UUID, err)
+		}
+	}
+
+	return svc.DeleteServerAndStorages(ctx, &request.DeleteServerAndStoragesRequest{
+		UUID: uuid,
+	})
+}
@@ -0,0 +1,448 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// The OS disk cloned from the template is always attached at this address,
+// which is how it is told apart from the devices managed in storage_devices.
+const osStorageAddress = "virtio:0"
+
+type storageDeviceChanges struct {
+	// devices from state which have to be detached
+	detach []storageDeviceModel
+	// detached devices which were created by this resource and are no longer used
+	remove []storageDeviceModel
+	// devices from state which have to be resized, with the planned size
+	resize []storageDeviceModel
+	// indexes of planned devices which have to be created and attached
+	create []int
+	// indexes of planned devices (existing storages) which have to be attached
+	attach []int
+}
+
+func (c storageDeviceChanges) isEmpty() bool {
+	return len(c.detach) == 0 && len(c.resize) == 0 && len(c.create) == 0 && len(c.attach) == 0
+}
+
+func buildStorageDevicesForServer(hostname string, devices []storageDeviceModel) (request.CreateServerStorageDeviceSlice, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	response := make(request.CreateServerStorageDeviceSlice, 0, len(devices))
+	for i, device := range devices {
+		if device.Storage.IsNull() || device.Storage.IsUnknown() {
+			if device.Type.ValueString() == upcloud.StorageTypeCDROM {
+				diags.AddAttributeError(
+					path.Root("storage_devices").AtListIndex(i).AtName("storage"),
+					"Missing storage",
+					"CD-ROM devices can not be created, `storage` has to point to an existing storage.",
+				)
+				continue
+			}
+			response = append(response, request.CreateServerStorageDevice{
+				Action:  request.CreateServerStorageDeviceActionCreate,
+				Address: device.Address.ValueString(),
+				Size:    int(device.Size.ValueInt64()),
+				Tier:    device.Tier.ValueString(),
+				Title:   storageDeviceTitle(hostname, i, device),
+				Type:    device.Type.ValueString(),
+			})
+			continue
+		}
+
+		response = append(response, request.CreateServerStorageDevice{
+			Action:  request.CreateServerStorageDeviceActionAttach,
+			Address: device.Address.ValueString(),
+			Storage: device.Storage.ValueString(),
+			Type:    device.Type.ValueString(),
+		})
+	}
+
+	return response, diags
+}
+
+func storageDeviceTitle(hostname string, index int, device storageDeviceModel) string {
+	if !device.Title.IsNull() && !device.Title.IsUnknown() {
+		return device.Title.ValueString()
+	}
+	return fmt.Sprintf("%s-disk%d", hostname, index+1)
+}
+
+// storageAddressEquals reports whether the planned address matches the current one.
+// Bus-only addresses (e.g. `virtio`) match any position on that bus.
+func storageAddressEquals(current, planned types.String) bool {
+	if planned.IsNull() || planned.IsUnknown() {
+		return true
+	}
+	if current.ValueString() == planned.ValueString() {
+		return true
+	}
+	return !strings.Contains(planned.ValueString(), ":") && strings.HasPrefix(current.ValueString(), planned.ValueString()+":")
+}
+
+func storageAttachmentEquals(current, planned storageDeviceModel) bool {
+	if !planned.Type.IsUnknown() && current.Type.ValueString() != planned.Type.ValueString() {
+		return false
+	}
+	return storageAddressEquals(current.Address, planned.Address)
+}
+
+// knownStringEquals reports whether the configured value is set and equals the current one.
+func knownStringEquals(current, configured types.String) bool {
+	return !configured.IsNull() && !configured.IsUnknown() && current.ValueString() == configured.ValueString()
+}
+
+// matchStorageDevices returns for each configured device the position of the device in state it
+// keeps, or -1 when a new storage is created for it. Existing storages are matched by UUID. Blocks
+// creating a disk are matched to the disks created by this resource by title, full address and
+// size, and only then in order, so that removing a block does not shift the disks of the others.
+func matchStorageDevices(state, config []storageDeviceModel) []int {
+	matches := make([]int, len(config))
+	claimed := make([]bool, len(state))
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	for i, c := range config {
+		if c.Storage.IsNull() || c.Storage.IsUnknown() {
+			continue
+		}
+		for j, s := range state {
+			if !claimed[j] && s.Storage.ValueString() == c.Storage.ValueString() {
+				matches[i] = j
+				claimed[j] = true
+				break
+			}
+		}
+	}
+
+	for _, same := range []func(s, c storageDeviceModel) bool{
+		func(s, c storageDeviceModel) bool { return knownStringEquals(s.Title, c.Title) },
+		// bus-only addresses do not identify a device
+		func(s, c storageDeviceModel) bool {
+			return strings.Contains(c.Address.ValueString(), ":") && knownStringEquals(s.Address, c.Address)
+		},
+		func(s, c storageDeviceModel) bool { return !c.Size.IsUnknown() && s.Size.Equal(c.Size) },
+		func(s, c storageDeviceModel) bool { return true },
+	} {
+		for i, c := range config {
+			if matches[i] >= 0 || !c.Storage.IsNull() {
+				continue
+			}
+			for j, s := range state {
+				// size is only tracked for the storages created by this resource
+				if claimed[j] || s.Size.IsNull() || !storageTierMatches(s, c) || !same(s, c) {
+					continue
+				}
+				matches[i] = j
+				claimed[j] = true
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// storageTierMatches reports whether the current disk can be kept for the configured tier;
+// the tier of a disk can not be changed.
+func storageTierMatches(current, configured storageDeviceModel) bool {
+	if configured.Tier.IsNull() {
+		return true
+	}
+	return knownStringEquals(current.Tier, configured.Tier)
+}
+
+// validateStorageDeviceSizes checks that no disk created by this resource is planned to shrink,
+// which UpCloud rejects only after the server has been stopped.
+func validateStorageDeviceSizes(state, plan []storageDeviceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	stateByUUID := make(map[string]storageDeviceModel, len(state))
+	for _, device := range state {
+		stateByUUID[device.Storage.ValueString()] = device
+	}
+	for i, device := range plan {
+		if device.Storage.IsNull() || device.Storage.IsUnknown() || device.Size.IsNull() || device.Size.IsUnknown() {
+			continue
+		}
+		current, ok := stateByUUID[device.Storage.ValueString()]
+		if !ok || current.Size.IsNull() || device.Size.ValueInt64() >= current.Size.ValueInt64() {
+			continue
+		}
+		diags.AddAttributeError(
+			path.Root("storage_devices").AtListIndex(i).AtName("size"),
+			"Invalid Attribute Value",
+			fmt.Sprintf("Disks can only be grown, storage %s is %d GB, got %d GB.", device.Storage.ValueString(), current.Size.ValueInt64(), device.Size.ValueInt64()),
+		)
+	}
+
+	return diags
+}
+
+func planStorageDeviceChanges(state, plan []storageDeviceModel) storageDeviceChanges {
+	var changes storageDeviceChanges
+
+	stateByUUID := make(map[string]storageDeviceModel, len(state))
+	for _, device := range state {
+		stateByUUID[device.Storage.ValueString()] = device
+	}
+
+	planned := make(map[string]bool)
+	kept := make(map[string]bool)
+	for i, device := range plan {
+		if device.Storage.IsNull() || device.Storage.IsUnknown() {
+			changes.create = append(changes.create, i)
+			continue
+		}
+
+		uuid := device.Storage.ValueString()
+		planned[uuid] = true
+		current, ok := stateByUUID[uuid]
+		if !ok || !storageAttachmentEquals(current, device) {
+			changes.attach = append(changes.attach, i)
+			continue
+		}
+
+		kept[uuid] = true
+		if !current.Size.IsNull() && !device.Size.IsNull() && !device.Size.IsUnknown() && current.Size.ValueInt64() != device.Size.ValueInt64() {
+			current.Size = device.Size
+			changes.resize = append(changes.resize, current)
+		}
+	}
+
+	for _, device := range state {
+		uuid := device.Storage.ValueString()
+		if kept[uuid] {
+			continue
+		}
+		changes.detach = append(changes.detach, device)
+		// size is only tracked for the storages created by this resource
+		if !planned[uuid] && !device.Size.IsNull() {
+			changes.remove = append(changes.remove, device)
+		}
+	}
+
+	return changes
+}
+
+func reconfigureServerStorageDevices(ctx context.Context, svc *service.Service, data *serverModel, changes storageDeviceChanges) error {
+	// assert server is stopped
+	s, err := svc.GetServerDetails(ctx, &request.GetServerDetailsRequest{
+		UUID: data.ID.ValueString(),
+	})
+	if err != nil {
+		return err
+	}
+	if s.State != upcloud.ServerStateStopped {
+		return errors.New("server needs to be stopped to alter storage devices")
+	}
+
+	for _, device := range changes.detach {
+		if _, err := svc.DetachStorage(ctx, &request.DetachStorageRequest{
+			ServerUUID: data.ID.ValueString(),
+			Address:    device.Address.ValueString(),
+		}); err != nil {
+			return fmt.Errorf("unable to detach storage %s; %w", device.Storage.ValueString(), err)
+		}
+	}
+
+	for _, device := range changes.remove {
+		if err := svc.DeleteStorage(ctx, &request.DeleteStorageRequest{
+			UUID: device.Storage.ValueString(),
+		}); err != nil {
+			return fmt.Errorf("unable to delete storage %s; %w", device.Storage.ValueString(), err)
+		}
+	}
+
+	for _, device := range changes.resize {
+		if _, err := svc.ModifyStorage(ctx, &request.ModifyStorageRequest{
+			UUID: device.Storage.ValueString(),
+			Size: int(device.Size.ValueInt64()),
+		}); err != nil {
+			return fmt.Errorf("unable to resize storage %s; %w", device.Storage.ValueString(), err)
+		}
+	}
+
+	for _, i := range changes.create {
+		device := data.StorageDevices[i]
+		if device.Type.ValueString() == upcloud.StorageTypeCDROM {
+			return fmt.Errorf("unable to create storage device #%d; CD-ROM devices require an existing storage", i)
+		}
+		storage, err := svc.CreateStorage(ctx, &request.CreateStorageRequest{
+			Size:  int(device.Size.ValueInt64()),
+			Tier:  device.Tier.ValueString(),
+			Title: storageDeviceTitle(data.Hostname.ValueString(), i, device),
+			Zone:  data.Zone.ValueString(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to create storage device #%d; %w", i, err)
+		}
+		if _, err := svc.WaitForStorageState(ctx, &request.WaitForStorageStateRequest{
+			UUID:         storage.UUID,
+			DesiredState: upcloud.StorageStateOnline,
+		}); err != nil {
+			return fmt.Errorf("unable to create storage device #%d; %w", i, err)
+		}
+		data.StorageDevices[i].Storage = types.StringValue(storage.UUID)
+		changes.attach = append(changes.attach, i)
+	}
+
+	for _, i := range changes.attach {
+		device := data.StorageDevices[i]
+		if _, err := svc.AttachStorage(ctx, &request.AttachStorageRequest{
+			ServerUUID:  data.ID.ValueString(),
+			Type:        device.Type.ValueString(),
+			Address:     device.Address.ValueString(),
+			StorageUUID: device.Storage.ValueString(),
+		}); err != nil {
+			return fmt.Errorf("unable to attach storage %s; %w", device.Storage.ValueString(), err)
+		}
+	}
+
+	return nil
+}
+
+// detachAttachedStorageDevices detaches the existing storages attached through storage_devices,
+// so that deleting the server together with its storages leaves them intact.
+func detachAttachedStorageDevices(ctx context.Context, svc *service.Service, data serverModel) error {
+	for _, device := range data.StorageDevices {
+		// size is only tracked for the storages created by this resource
+		if !device.Size.IsNull() {
+			continue
+		}
+		if _, err := svc.DetachStorage(ctx, &request.DetachStorageRequest{
+			ServerUUID: data.ID.ValueString(),
+			Address:    device.Address.ValueString(),
+		}); err != nil {
+			return fmt.Errorf("unable to detach storage %s; %w", device.Storage.ValueString(), err)
+		}
+	}
+	return nil
+}
+
+// deleteServerKeepingStorages deletes only the server and logs the storages left behind,
+// so that they can be attached to a replacement server.
+func deleteServerKeepingStorages(ctx context.Context, svc *service.Service, uuid string) error {
+	s, err := svc.GetServerDetails(ctx, &request.GetServerDetailsRequest{
+		UUID: uuid,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := svc.DeleteServer(ctx, &request.DeleteServerRequest{
+		UUID: uuid,
+	}); err != nil {
+		return err
+	}
+
+	for _, device := range s.StorageDevices {
+		tflog.Info(ctx, "keeping storage of deleted server", map[string]interface{}{
+			"server":  uuid,
+			"storage": device.UUID,
+			"address": device.Address,
+			"title":   device.Title,
+		})
+	}
+	return nil
+}
+
+// storageDevicesFromServerDetails keeps the order of the prior devices so that the
+// refreshed list lines up with the configuration; unknown devices are appended.
+func storageDevicesFromServerDetails(prior []storageDeviceModel, devices upcloud.ServerStorageDeviceSlice) []storageDeviceModel {
+	claimed := make(map[string]bool)
+	for _, p := range prior {
+		if !p.Storage.IsNull() && !p.Storage.IsUnknown() {
+			claimed[p.Storage.ValueString()] = true
+		}
+	}
+
+	managed := make([]upcloud.ServerStorageDevice, 0, len(devices))
+	unclaimed := make([]upcloud.ServerStorageDevice, 0)
+	byUUID := make(map[string]upcloud.ServerStorageDevice)
+	for _, device := range devices {
+		if device.Address == osStorageAddress {
+			continue
+		}
+		managed = append(managed, device)
+		byUUID[device.UUID] = device
+		if !claimed[device.UUID] {
+			unclaimed = append(unclaimed, device)
+		}
+	}
+
+	used := make(map[string]bool)
+	response := make([]storageDeviceModel, 0, len(managed))
+	for _, p := range prior {
+		var device upcloud.ServerStorageDevice
+		if !p.Storage.IsNull() && !p.Storage.IsUnknown() {
+			d, ok := byUUID[p.Storage.ValueString()]
+			if !ok {
+				continue
+			}
+			device = d
+		} else {
+			// created together with the server, so the UUID is not known yet
+			if len(unclaimed) == 0 {
+				continue
+			}
+			device, unclaimed = unclaimed[0], unclaimed[1:]
+		}
+		used[device.UUID] = true
+		response = append(response, storageDeviceToModel(p, device))
+	}
+
+	for _, device := range managed {
+		if !used[device.UUID] {
+			response = append(response, storageDeviceToModel(storageDeviceModel{}, device))
+		}
+	}
+
+	return response
+}
+
+// adoptStorageDevices tracks the size of every device of an imported server, so that its storages
+// are handled like the ones created by this resource and deleted together with the server.
+func adoptStorageDevices(devices []storageDeviceModel, details upcloud.ServerStorageDeviceSlice) {
+	sizes := make(map[string]int)
+	for _, device := range details {
+		sizes[device.UUID] = device.Size
+	}
+	for i, device := range devices {
+		if size, ok := sizes[device.Storage.ValueString()]; ok {
+			devices[i].Size = types.Int64Value(int64(size))
+		}
+	}
+}
+
+func storageDeviceToModel(prior storageDeviceModel, device upcloud.ServerStorageDevice) storageDeviceModel {
+	m := storageDeviceModel{
+		Storage: types.StringValue(device.UUID),
+		Address: types.StringValue(device.Address),
+		Type:    types.StringValue(device.Type),
+		Tier:    types.StringValue(device.Tier),
+		Title:   types.StringValue(device.Title),
+		Size:    types.Int64Null(),
+	}
+	// keep bus-only addresses as configured
+	if storageAddressEquals(m.Address, prior.Address) && !prior.Address.IsNull() && !prior.Address.IsUnknown() {
+		m.Address = prior.Address
+	}
+	// size is only tracked for the storages created by this resource
+	if !prior.Size.IsNull() {
+		m.Size = types.Int64Value(int64(device.Size))
+	}
+	return m
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanStorageDeviceChanges(t *testing.T) {
+	created := storageDeviceModel{
+		Storage: types.StringValue("created"),
+		Address: types.StringValue("virtio:1"),
+		Type:    types.StringValue(upcloud.StorageTypeDisk),
+		Size:    types.Int64Value(10),
+	}
+	attached := storageDeviceModel{
+		Storage: types.StringValue("attached"),
+		Address: types.StringValue("virtio:2"),
+		Type:    types.StringValue(upcloud.StorageTypeDisk),
+	}
+
+	testCases := []struct {
+		name   string
+		state  []storageDeviceModel
+		plan   []storageDeviceModel
+		detach int
+		remove int
+		resize int
+		create int
+		attach int
+	}{
+		{
+			name:  "no changes",
+			state: []storageDeviceModel{created, attached},
+			plan:  []storageDeviceModel{created, attached},
+		},
+		{
+			name:   "new blank disk",
+			state:  []storageDeviceModel{created},
+			plan:   []storageDeviceModel{created, {Storage: types.StringUnknown(), Size: types.Int64Value(5)}},
+			create: 1,
+		},
+		{
+			name:   "attach existing storage",
+			state:  []storageDeviceModel{created},
+			plan:   []storageDeviceModel{created, attached},
+			attach: 1,
+		},
+		{
+			name:   "detach attached storage is not deleted",
+			state:  []storageDeviceModel{created, attached},
+			plan:   []storageDeviceModel{created},
+			detach: 1,
+		},
+		{
+			name:   "detach created storage is deleted",
+			state:  []storageDeviceModel{created, attached},
+			plan:   []storageDeviceModel{attached},
+			detach: 1,
+			remove: 1,
+		},
+		{
+			name:  "address change reattaches",
+			state: []storageDeviceModel{attached},
+			plan: []storageDeviceModel{{
+				Storage: attached.Storage,
+				Address: types.StringValue("scsi:0:0"),
+				Type:    attached.Type,
+			}},
+			detach: 1,
+			attach: 1,
+		},
+		{
+			name:  "bus only address matches",
+			state: []storageDeviceModel{attached},
+			plan: []storageDeviceModel{{
+				Storage: attached.Storage,
+				Address: types.StringValue("virtio"),
+				Type:    attached.Type,
+			}},
+		},
+		{
+			name:  "size change resizes",
+			state: []storageDeviceModel{created},
+			plan: []storageDeviceModel{{
+				Storage: created.Storage,
+				Address: created.Address,
+				Type:    created.Type,
+				Size:    types.Int64Value(20),
+			}},
+			resize: 1,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			changes := planStorageDeviceChanges(testCase.state, testCase.plan)
+			assert.Len(t, changes.detach, testCase.detach)
+			assert.Len(t, changes.remove, testCase.remove)
+			assert.Len(t, changes.resize, testCase.resize)
+			assert.Len(t, changes.create, testCase.create)
+			assert.Len(t, changes.attach, testCase.attach)
+		})
+	}
+}
+
+func TestMatchStorageDevices(t *testing.T) {
+	disk := func(uuid, title string, size int64) storageDeviceModel {
+		return storageDeviceModel{
+			Storage: types.StringValue(uuid),
+			Address: types.StringValue("virtio"),
+			Tier:    types.StringValue(upcloud.StorageTierMaxIOPS),
+			Title:   types.StringValue(title),
+			Size:    types.Int64Value(size),
+		}
+	}
+	block := func(title string, size int64) storageDeviceModel {
+		device := storageDeviceModel{
+			Storage: types.StringNull(),
+			Address: types.StringNull(),
+			Tier:    types.StringNull(),
+			Title:   types.StringNull(),
+			Size:    types.Int64Value(size),
+		}
+		if title != "" {
+			device.Title = types.StringValue(title)
+		}
+		return device
+	}
+	a := disk("a", "a", 10)
+	b := disk("b", "b", 20)
+	attached := storageDeviceModel{Storage: types.StringValue("attached"), Size: types.Int64Null()}
+
+	testCases := []struct {
+		name   string
+		state  []storageDeviceModel
+		config []storageDeviceModel
+		want   []int
+	}{
+		{
+			name:   "unchanged",
+			state:  []storageDeviceModel{a, b},
+			config: []storageDeviceModel{block("", 10), block("", 20)},
+			want:   []int{0, 1},
+		},
+		{
+			name:   "first removed",
+			state:  []storageDeviceModel{a, b},
+			config: []storageDeviceModel{block("", 20)},
+			want:   []int{1},
+		},
+		{
+			name:   "reordered by title",
+			state:  []storageDeviceModel{a, b},
+			config: []storageDeviceModel{block("b", 30), block("a", 10)},
+			want:   []int{1, 0},
+		},
+		{
+			name:   "resized",
+			state:  []storageDeviceModel{a},
+			config: []storageDeviceModel{block("", 15)},
+			want:   []int{0},
+		},
+		{
+			name:   "new disk",
+			state:  []storageDeviceModel{a},
+			config: []storageDeviceModel{block("", 10), block("c", 5)},
+			want:   []int{0, -1},
+		},
+		{
+			name:   "other tier",
+			state:  []storageDeviceModel{a},
+			config: []storageDeviceModel{{Storage: types.StringNull(), Tier: types.StringValue(upcloud.StorageTierStandard), Size: types.Int64Value(10)}},
+			want:   []int{-1},
+		},
+		{
+			name:   "attached storage",
+			state:  []storageDeviceModel{attached, a},
+			config: []storageDeviceModel{{Storage: types.StringValue("attached")}, block("", 10)},
+			want:   []int{0, 1},
+		},
+		{
+			name:   "attached storage is not reused for new disk",
+			state:  []storageDeviceModel{attached},
+			config: []storageDeviceModel{block("", 10)},
+			want:   []int{-1},
+		},
+		{
+			name:   "unknown storage",
+			state:  []storageDeviceModel{a},
+			config: []storageDeviceModel{{Storage: types.StringUnknown(), Size: types.Int64Value(10)}},
+			want:   []int{-1},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.want, matchStorageDevices(testCase.state, testCase.config))
+		})
+	}
+}
+
+func TestValidateStorageDeviceSizes(t *testing.T) {
+	state := []storageDeviceModel{
+		{Storage: types.StringValue("created"), Size: types.Int64Value(20)},
+		{Storage: types.StringValue("attached"), Size: types.Int64Null()},
+	}
+
+	testCases := []struct {
+		name    string
+		plan    []storageDeviceModel
+		wantErr bool
+	}{
+		{
+			name: "grown",
+			plan: []storageDeviceModel{{Storage: types.StringValue("created"), Size: types.Int64Value(30)}},
+		},
+		{
+			name:    "shrunk",
+			plan:    []storageDeviceModel{{Storage: types.StringValue("created"), Size: types.Int64Value(10)}},
+			wantErr: true,
+		},
+		{
+			name: "new disk",
+			plan: []storageDeviceModel{{Storage: types.StringUnknown(), Size: types.Int64Value(10)}},
+		},
+		{
+			name: "attached storage",
+			plan: []storageDeviceModel{{Storage: types.StringValue("attached"), Size: types.Int64Null()}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			diags := validateStorageDeviceSizes(state, testCase.plan)
+			assert.Equal(t, testCase.wantErr, diags.HasError())
+		})
+	}
+}
+
+func TestStorageDevicesFromServerDetails(t *testing.T) {
+	devices := upcloud.ServerStorageDeviceSlice{
+		{UUID: "os", Address: osStorageAddress, Type: upcloud.StorageTypeDisk, Size: 20},
+		{UUID: "new", Address: "virtio:1", Type: upcloud.StorageTypeDisk, Size: 10},
+		{UUID: "attached", Address: "virtio:2", Type: upcloud.StorageTypeDisk, Size: 50},
+		{UUID: "manual", Address: "virtio:3", Type: upcloud.StorageTypeDisk, Size: 30},
+	}
+	prior := []storageDeviceModel{
+		{Storage: types.StringValue("attached"), Address: types.StringValue("virtio")},
+		{Storage: types.StringUnknown(), Size: types.Int64Value(10)},
+	}
+
+	result := storageDevicesFromServerDetails(prior, devices)
+
+	assert.Len(t, result, 3)
+	assert.Equal(t, "attached", result[0].Storage.ValueString())
+	assert.Equal(t, "virtio", result[0].Address.ValueString())
+	assert.True(t, result[0].Size.IsNull())
+	assert.Equal(t, "new", result[1].Storage.ValueString())
+	assert.Equal(t, int64(10), result[1].Size.ValueInt64())
+	assert.Equal(t, "manual", result[2].Storage.ValueString())
+}
+
+func TestAdoptStorageDevices(t *testing.T) {
+	details := upcloud.ServerStorageDeviceSlice{
+		{UUID: "os", Address: osStorageAddress, Type: upcloud.StorageTypeDisk, Size: 20},
+		{UUID: "data", Address: "virtio:1", Type: upcloud.StorageTypeDisk, Size: 50},
+	}
+	devices := storageDevicesFromServerDetails(nil, details)
+	assert.True(t, devices[0].Size.IsNull())
+
+	adoptStorageDevices(devices, details)
+
+	assert.Len(t, devices, 1)
+	assert.Equal(t, int64(50), devices[0].Size.ValueInt64())
+}
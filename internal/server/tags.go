@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+func tagsFromModel(ctx context.Context, tags types.Set) ([]string, diag.Diagnostics) {
+	if tags.IsNull() || tags.IsUnknown() {
+		return nil, nil
+	}
+
+	response := make([]string, 0, len(tags.Elements()))
+	if diags := tags.ElementsAs(ctx, &response, false); diags.HasError() {
+		return nil, diags
+	}
+	sort.Strings(response)
+
+	return response, nil
+}
+
+func tagsFromServerDetails(prior types.Set, tags upcloud.ServerTagSlice) (types.Set, diag.Diagnostics) {
+	// keep an explicitly empty set instead of turning it into null
+	if len(tags) == 0 && (prior.IsNull() || len(prior.Elements()) == 0) {
+		return prior, nil
+	}
+
+	priorTags, diags := tagsFromModel(context.Background(), prior)
+	if diags.HasError() {
+		return prior, diags
+	}
+
+	// keep the spelling used in the configuration
+	response := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		for _, p := range priorTags {
+			if strings.EqualFold(p, tag) {
+				tag = p
+				break
+			}
+		}
+		response = append(response, tag)
+	}
+
+	return types.SetValueFrom(context.Background(), types.StringType, response)
+}
+
+// diffTags returns the tags which have to be assigned to and removed from the server.
+func diffTags(current, planned []string) (assign, unassign []string) {
+	for _, tag := range planned {
+		if !containsTag(current, tag) {
+			assign = append(assign, tag)
+		}
+	}
+	for _, tag := range current {
+		if !containsTag(planned, tag) {
+			unassign = append(unassign, tag)
+		}
+	}
+	return assign, unassign
+}
+
+// tag names are case-insensitive in UpCloud
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// createMissingTags creates the tags which do not exist yet. Tags are shared by all
+// servers of the account, so they are never deleted by this resource.
+func createMissingTags(ctx context.Context, svc *service.Service, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	existing, err := svc.GetTags(ctx)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(existing.Tags))
+	for _, t := range existing.Tags {
+		names = append(names, t.Name)
+	}
+
+	for _, tag := range tags {
+		if containsTag(names, tag) {
+			continue
+		}
+		tflog.Info(ctx, "creating tag", map[string]interface{}{"tag": tag})
+		_, err := svc.CreateTag(ctx, &request.CreateTagRequest{
+			Tag: upcloud.Tag{Name: tag},
+		})
+		// the tag might have been created meanwhile by another server
+		var problem *upcloud.Problem
+		if errors.As(err, &problem) && problem.ErrorCode() == upcloud.ErrCodeTagExists {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to create tag %s; %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+func reconfigureServerTags(ctx context.Context, svc *service.Service, uuid string, current, planned []string) error {
+	assign, unassign := diffTags(current, planned)
+
+	if err := createMissingTags(ctx, svc, assign); err != nil {
+		return err
+	}
+
+	if len(assign) > 0 {
+		if _, err := svc.TagServer(ctx, &request.TagServerRequest{
+			UUID: uuid,
+			Tags: assign,
+		}); err != nil {
+			return fmt.Errorf("unable to assign tags; %w", err)
+		}
+	}
+
+	if len(unassign) > 0 {
+		if _, err := svc.UntagServer(ctx, &request.UntagServerRequest{
+			UUID: uuid,
+			Tags: unassign,
+		}); err != nil {
+			return fmt.Errorf("unable to unassign tags; %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		current  []string
+		planned  []string
+		assign   []string
+		unassign []string
+	}{
+		{
+			name:    "new tags",
+			current: nil,
+			planned: []string{"dev", "web"},
+			assign:  []string{"dev", "web"},
+		},
+		{
+			name:     "removed tags",
+			current:  []string{"dev", "web"},
+			planned:  []string{"web"},
+			unassign: []string{"dev"},
+		},
+		{
+			name:     "replaced tags",
+			current:  []string{"dev"},
+			planned:  []string{"prod"},
+			assign:   []string{"prod"},
+			unassign: []string{"dev"},
+		},
+		{
+			name:    "case insensitive match",
+			current: []string{"DEV"},
+			planned: []string{"dev"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assign, unassign := diffTags(testCase.current, testCase.planned)
+			assert.Equal(t, testCase.assign, assign)
+			assert.Equal(t, testCase.unassign, unassign)
+		})
+	}
+}
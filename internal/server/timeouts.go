@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	defaultCreateTimeout = 20 * time.Minute
+	defaultUpdateTimeout = 20 * time.Minute
+	defaultDeleteTimeout = 10 * time.Minute
+
+	// time given to look up the server state after the operation timed out
+	lastKnownStateTimeout = 30 * time.Second
+
+	// seconds to wait for a soft stop before the server is hard stopped
+	defaultShutdownTimeout = 120
+)
+
+// reportTimeout adds a diagnostic naming the timed out phase and the last known server state,
+// if the operation context hit its deadline. It is meant to be deferred.
+func reportTimeout(ctx context.Context, svc *service.Service, diags *diag.Diagnostics, phase string, timeout time.Duration, id *types.String) {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return
+	}
+
+	state := "unknown"
+	if id != nil && !id.IsNull() && !id.IsUnknown() {
+		lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lastKnownStateTimeout)
+		defer cancel()
+		if details, err := svc.GetServerDetails(lookupCtx, &request.GetServerDetailsRequest{UUID: id.ValueString()}); err == nil {
+			state = details.State
+		}
+	}
+
+	diags.AddError(
+		"Timeout Error",
+		fmt.Sprintf("Server %s timed out after %s; last known server state: %s", phase, timeout, state),
+	)
+}
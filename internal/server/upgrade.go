@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// serverSchemaVersion has to be bumped, and an upgrader added, whenever the shape of the stored state changes.
+//
+//   - 0: v0.1.0, only id, hostname, zone and public network interfaces
+//   - 1: the current schema; adds storage devices, login, firewall, labels, tags, plan and hardware
+//     settings, power, placement, remote access, stop, restart and delete settings, timeouts, and
+//     network_interface.index and additional_ip_address
+const serverSchemaVersion = 1
+
+type serverModelV0 struct {
+	ID               types.String              `tfsdk:"id"`
+	Hostname         types.String              `tfsdk:"hostname"`
+	Zone             types.String              `tfsdk:"zone"`
+	NetworkInterface []networkInterfaceModelV0 `tfsdk:"network_interface"`
+}
+
+type networkInterfaceModelV0 struct {
+	IpAddressFamily   types.String `tfsdk:"ip_address_family"`
+	IpAddress         types.String `tfsdk:"ip_address"`
+	IpAddressFloating types.Bool   `tfsdk:"ip_address_floating"`
+	MacAddress        types.String `tfsdk:"mac_address"`
+	Type              types.String `tfsdk:"type"`
+	Network           types.String `tfsdk:"network"`
+	SourceIpFiltering types.Bool   `tfsdk:"source_ip_filtering"`
+	Bootable          types.Bool   `tfsdk:"bootable"`
+}
+
+func (r *serverResource) UpgradeState(_ context.Context) map[int64]resource.StateUpgrader {
+	schemaV0 := serverSchemaV0()
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema:   &schemaV0,
+			StateUpgrader: upgradeServerStateV0,
+		},
+	}
+}
+
+// serverSchemaV0 describes the stored state of v0.1.0; only the types matter.
+func serverSchemaV0() schema.Schema {
+	return schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id":       schema.StringAttribute{Computed: true},
+			"hostname": schema.StringAttribute{Required: true},
+			"zone":     schema.StringAttribute{Required: true},
+		},
+		Blocks: map[string]schema.Block{
+			"network_interface": schema.ListNestedBlock{
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"ip_address_family":   schema.StringAttribute{Optional: true, Computed: true},
+						"ip_address":          schema.StringAttribute{Computed: true},
+						"ip_address_floating": schema.BoolAttribute{Computed: true},
+						"mac_address":         schema.StringAttribute{Computed: true},
+						"type":                schema.StringAttribute{Computed: true},
+						"network":             schema.StringAttribute{Computed: true},
+						"source_ip_filtering": schema.BoolAttribute{Computed: true},
+						"bootable":            schema.BoolAttribute{Computed: true},
+					},
+				},
+			},
+		},
+	}
+}
+
+func upgradeServerStateV0(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var prior serverModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	interfaces := make([]networkInterfaceModel, 0, len(prior.NetworkInterface))
+	for _, iface := range prior.NetworkInterface {
+		interfaces = append(interfaces, networkInterfaceModel{
+			IpAddressFamily:   iface.IpAddressFamily,
+			IpAddress:         iface.IpAddress,
+			IpAddressFloating: iface.IpAddressFloating,
+			MacAddress:        iface.MacAddress,
+			Type:              iface.Type,
+			Network:           iface.Network,
+			SourceIpFiltering: iface.SourceIpFiltering,
+			Bootable:          iface.Bootable,
+		})
+	}
+
+	// everything else is left null and filled in by the refresh following the upgrade
+	resp.State.Raw = tftypes.NewValue(resp.State.Schema.Type().TerraformType(ctx), nil)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), prior.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("hostname"), prior.Hostname)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("zone"), prior.Zone)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("network_interface"), interfaces)...)
+	resp.Diagnostics.Append(setConfigOnlyDefaults(ctx, &resp.State)...)
+}
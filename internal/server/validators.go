@@ -2,23 +2,182 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 )
 
-func validateZone(ctx context.Context, service *service.Service, zone string) error {
+const (
+	zoneTypePublic  = "public"
+	zoneTypePrivate = "private"
+
+	// maximum edit distance of the zones suggested for a mistyped zone
+	maxZoneSuggestionDistance = 3
+)
+
+func validateZone(ctx context.Context, service *service.Service, zone, zoneType string) error {
 	zones, err := service.GetZones(ctx)
 	if err != nil {
 		return err
 	}
+	return checkZone(zones.Zones, zone, zoneType)
+}
+
+// checkZone verifies that zone is one of the available zones of the given type (any if empty)
+// and suggests the closest zone names otherwise.
+func checkZone(zones []upcloud.Zone, zone, zoneType string) error {
 	availableZones := make([]string, 0)
-	for _, z := range zones.Zones {
+	for _, z := range zones {
+		matchesType := zoneType == "" || (zoneType == zoneTypePublic) == z.Public.Bool()
 		if z.ID == zone {
+			if matchesType {
+				return nil
+			}
+			return fmt.Errorf("expected a %s zone, got %s", zoneType, zone)
+		}
+		if matchesType {
+			availableZones = append(availableZones, z.ID)
+		}
+	}
+
+	msg := fmt.Sprintf("expected zone to be one of [%s], got %s", strings.Join(availableZones, ", "), zone)
+	if suggestions := suggestZones(zone, availableZones); len(suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, " or "))
+	}
+	return errors.New(msg)
+}
+
+// suggestZones returns the zones closest to the given one, nearest first.
+func suggestZones(zone string, zones []string) []string {
+	distances := make(map[string]int)
+	suggestions := make([]string, 0)
+	for _, z := range zones {
+		d := editDistance(strings.ToLower(zone), z)
+		if d > maxZoneSuggestionDistance {
+			continue
+		}
+		distances[z] = d
+		suggestions = append(suggestions, z)
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return distances[suggestions[i]] < distances[suggestions[j]]
+	})
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+var storageAddressRegexp = regexp.MustCompile(`^(virtio|scsi|ide)(:[0-9]+(:[0-9]+)?)?$`)
+
+// UpCloud accepts at most 64 KiB of user data
+const maxUserDataSize = 64 * 1024
+
+// userDataSizeValidator checks the size of user_data in bytes, as multibyte characters count
+// towards the API limit by their encoded length.
+type userDataSizeValidator struct{}
+
+func (v userDataSizeValidator) Description(_ context.Context) string {
+	return fmt.Sprintf("size must be at most %d bytes", maxUserDataSize)
+}
+
+func (v userDataSizeValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v userDataSizeValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if size := len(req.ConfigValue.ValueString()); size > maxUserDataSize {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value Length",
+			fmt.Sprintf("Attribute %s %s, got %d bytes.", req.Path, v.Description(ctx), size),
+		)
+	}
+}
+
+var (
+	labelKeyRegexp            = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	labelKeyNotReservedRegexp = regexp.MustCompile(`^[^_]`)
+)
+
+var firewallPortRegexp = regexp.MustCompile(`^([1-9][0-9]{0,3}|[1-5][0-9]{4}|6[0-4][0-9]{3}|65[0-4][0-9]{2}|655[0-2][0-9]|6553[0-5])$`)
+
+var simpleBackupTimeRegexp = regexp.MustCompile(`^([01][0-9]|2[0-3])[0-5][0-9]$`)
+
+func validateTimezone(ctx context.Context, service *service.Service, timezone string) error {
+	timezones, err := service.GetTimeZones(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tz := range timezones.TimeZones {
+		if tz == timezone {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected timezone to be a valid timezone name (e.g. Europe/Helsinki), got %s", timezone)
+}
+
+var bootOrderRegexp = regexp.MustCompile(`^(disk|cdrom|network)(,(disk|cdrom|network)){0,2}$`)
+
+// UpCloud allows at most four additional addresses per interface
+const maxAdditionalIPAddresses = 4
+
+func validateInterfaceAddress(ctx context.Context, service *service.Service, network, address string) error {
+	details, err := service.GetNetworkDetails(ctx, &request.GetNetworkDetailsRequest{
+		UUID: network,
+	})
+	if err != nil {
+		return err
+	}
+	return checkAddressInNetwork(details, address)
+}
+
+// checkAddressInNetwork verifies that address is within one of the IP networks of the network.
+func checkAddressInNetwork(network *upcloud.Network, address string) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("expected a valid IP address, got %s", address)
+	}
+
+	cidrs := make([]string, 0, len(network.IPNetworks))
+	for _, ipNetwork := range network.IPNetworks {
+		_, cidr, err := net.ParseCIDR(ipNetwork.Address)
+		if err != nil {
+			continue
+		}
+		if cidr.Contains(ip) {
 			return nil
 		}
-		availableZones = append(availableZones, z.ID)
+		cidrs = append(cidrs, ipNetwork.Address)
 	}
-	return fmt.Errorf("expected zone to be one of [%s], got %s", strings.Join(availableZones, ", "), zone)
+	return fmt.Errorf("expected an address within network %s [%s], got %s", network.UUID, strings.Join(cidrs, ", "), address)
 }
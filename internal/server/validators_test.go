@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckZone(t *testing.T) {
+	zones := []upcloud.Zone{
+		{ID: "de-fra1", Public: upcloud.True},
+		{ID: "fi-hel1", Public: upcloud.True},
+		{ID: "fi-hel2", Public: upcloud.True},
+		{ID: "fi-hel2-private", Public: upcloud.False, ParentZone: "fi-hel2"},
+	}
+
+	testCases := []struct {
+		name     string
+		zone     string
+		zoneType string
+		err      string
+	}{
+		{
+			name: "existing zone",
+			zone: "de-fra1",
+		},
+		{
+			name: "mistyped zone",
+			zone: "fi-hell1",
+			err:  "did you mean fi-hel1 or fi-hel2?",
+		},
+		{
+			name: "unknown zone without suggestions",
+			zone: "us-nyc1",
+			err:  "expected zone to be one of [de-fra1, fi-hel1, fi-hel2, fi-hel2-private], got us-nyc1",
+		},
+		{
+			name:     "public zone",
+			zone:     "fi-hel1",
+			zoneType: zoneTypePublic,
+		},
+		{
+			name:     "private zone when public required",
+			zone:     "fi-hel2-private",
+			zoneType: zoneTypePublic,
+			err:      "expected a public zone, got fi-hel2-private",
+		},
+		{
+			name:     "only private zones suggested",
+			zone:     "fi-hel2-privat",
+			zoneType: zoneTypePrivate,
+			err:      "expected zone to be one of [fi-hel2-private], got fi-hel2-privat; did you mean fi-hel2-private?",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := checkZone(zones, testCase.zone, testCase.zoneType)
+			if testCase.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, testCase.err)
+		})
+	}
+}
+
+func TestCheckAddressInNetwork(t *testing.T) {
+	network := &upcloud.Network{
+		UUID: "03000000-0000-4000-8000-000000000001",
+		IPNetworks: upcloud.IPNetworkSlice{
+			{Address: "10.0.0.0/24", Family: upcloud.IPAddressFamilyIPv4},
+			{Address: "fd00::/64", Family: upcloud.IPAddressFamilyIPv6},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		address string
+		err     string
+	}{
+		{
+			name:    "IPv4 within network",
+			address: "10.0.0.10",
+		},
+		{
+			name:    "IPv6 within network",
+			address: "fd00::10",
+		},
+		{
+			name:    "outside network",
+			address: "10.0.1.10",
+			err:     "expected an address within network 03000000-0000-4000-8000-000000000001 [10.0.0.0/24, fd00::/64], got 10.0.1.10",
+		},
+		{
+			name:    "invalid address",
+			address: "10.0.0",
+			err:     "expected a valid IP address, got 10.0.0",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := checkAddressInNetwork(network, testCase.address)
+			if testCase.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
+
+func TestUserDataSizeValidator(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value types.String
+		error bool
+	}{
+		{
+			name:  "at the limit",
+			value: types.StringValue(strings.Repeat("a", maxUserDataSize)),
+		},
+		{
+			name:  "over the limit",
+			value: types.StringValue(strings.Repeat("a", maxUserDataSize+1)),
+			error: true,
+		},
+		{
+			name:  "multibyte characters over the limit",
+			value: types.StringValue(strings.Repeat("ä", maxUserDataSize/2+1)),
+			error: true,
+		},
+		{
+			name:  "unknown",
+			value: types.StringUnknown(),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := validator.StringRequest{
+				Path:        path.Root("user_data"),
+				ConfigValue: testCase.value,
+			}
+			resp := &validator.StringResponse{}
+			userDataSizeValidator{}.ValidateString(context.Background(), req, resp)
+			assert.Equal(t, testCase.error, resp.Diagnostics.HasError())
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package utils
+
+import (
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+)
+
+// ProviderData is what the provider hands over to its resources.
+type ProviderData struct {
+	Service *service.Service
+	// Client is used for the few API responses which service does not fully decode
+	Client service.Client
+}
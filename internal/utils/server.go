@@ -2,6 +2,8 @@ package utils
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
@@ -10,6 +12,15 @@ import (
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
+// IsServerNotFound reports whether err is the API problem returned for a server which does not exist.
+func IsServerNotFound(err error) bool {
+	var problem *upcloud.Problem
+	if !errors.As(err, &problem) {
+		return false
+	}
+	return problem.ErrorCode() == upcloud.ErrCodeServerNotFound || problem.Status == http.StatusNotFound
+}
+
 func VerifyServerStopped(ctx context.Context, stopRequest request.StopServerRequest, meta interface{}) error {
 	if stopRequest.Timeout == 0 {
 		stopRequest.Timeout = time.Minute * 2
@@ -28,8 +39,9 @@ func VerifyServerStopped(ctx context.Context, stopRequest request.StopServerRequ
 		return err
 	}
 	if server.State != upcloud.ServerStateStopped {
-		// Soft stop with 2 minute timeout, after which hard stop occurs
-		tflog.Info(ctx, "stopping server", map[string]interface{}{"uuid": stopRequest.UUID})
+		// Soft stop with timeout (2 minutes by default), after which hard stop occurs
+		tflog.Info(ctx, "stopping server", map[string]interface{}{"uuid": stopRequest.UUID, "stop_type": stopRequest.StopType})
+		started := time.Now()
 		_, err := client.StopServer(ctx, &stopRequest)
 		if err != nil {
 			return err
@@ -41,6 +53,10 @@ func VerifyServerStopped(ctx context.Context, stopRequest request.StopServerRequ
 		if err != nil {
 			return err
 		}
+		// the API does not report the escalation, and the elapsed time includes the polling interval
+		if stopRequest.StopType == upcloud.StopTypeSoft && time.Since(started) >= stopRequest.Timeout {
+			tflog.Warn(ctx, "soft stop took longer than the timeout, server may have been hard stopped", map[string]interface{}{"uuid": stopRequest.UUID, "timeout": stopRequest.Timeout.String()})
+		}
 	}
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/upcloud-terraform-provider-server/internal/server"
+	"github.com/upcloud-terraform-provider-server/internal/utils"
 
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/client"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
@@ -121,13 +122,14 @@ func (p *upcloudProvider) Configure(ctx context.Context, req provider.ConfigureR
 	httpClient.RetryWaitMax = time.Duration(withInt64Default(model.RetryWaitMaxSec, 30)) * time.Second
 	httpClient.RetryMax = int(withInt64Default(model.RetryMax, 4))
 
-	service := newUpCloudServiceConnection(
+	apiClient := newUpCloudClient(
 		config.Username,
 		config.Password,
 		httpClient.HTTPClient,
 		requestTimeout,
 		p.userAgent,
 	)
+	service := service.New(apiClient)
 
 	_, err := config.checkLogin(service)
 	if err != nil {
@@ -136,7 +138,10 @@ func (p *upcloudProvider) Configure(ctx context.Context, req provider.ConfigureR
 
 	tflog.Info(ctx, "UpCloud service connection configured for plugin framework provider", map[string]interface{}{"http_client": fmt.Sprintf("%#v", httpClient), "request_timeout": requestTimeout})
 
-	resp.ResourceData = service
+	resp.ResourceData = &utils.ProviderData{
+		Service: service,
+		Client:  apiClient,
+	}
 	resp.DataSourceData = service
 }
 
@@ -150,7 +155,7 @@ func (p *upcloudProvider) Resources(_ context.Context) []func() resource.Resourc
 	}
 }
 
-func newUpCloudServiceConnection(username, password string, httpClient *http.Client, requestTimeout time.Duration, userAgents ...string) *service.Service {
+func newUpCloudClient(username, password string, httpClient *http.Client, requestTimeout time.Duration, userAgents ...string) *client.Client {
 	providerClient := client.New(
 		username,
 		password,
@@ -163,7 +168,7 @@ func newUpCloudServiceConnection(username, password string, httpClient *http.Cli
 	}
 	providerClient.UserAgent = strings.Join(userAgents, " ")
 
-	return service.New(providerClient)
+	return providerClient
 }
 
 func defaultUserAgent() string {
@@ -2,6 +2,7 @@ package upcloud
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
@@ -46,7 +47,84 @@ func TestAccServerResource(t *testing.T) {
 func testAccUpcloudServerResourceConfig(configurableAttribute string) string {
 	return fmt.Sprintf(`
 resource "upcloud_server" "test" {
-  zone = %[1]q
+  hostname = "tf-acc-server"
+  zone     = %[1]q
+
+  network_interface {
+    type = "public"
+  }
 }
 `, configurableAttribute)
 }
+
+// testAccNetworkEnv names the private network used by the tests attaching one.
+const testAccNetworkEnv = "UPCLOUD_TEST_NETWORK"
+
+func testAccNetworkPreCheck(t *testing.T) {
+	testAccPreCheck(t)
+	if os.Getenv(testAccNetworkEnv) == "" {
+		t.Skipf("%s must be set to the UUID of a private network in fi-hel1", testAccNetworkEnv)
+	}
+}
+
+func TestAccServerResourceImport(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccNetworkPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccUpcloudServerImportConfig(os.Getenv(testAccNetworkEnv)),
+			},
+			{
+				ResourceName:       "upcloud_server.test",
+				ImportState:        true,
+				ImportStateVerify:  true,
+				ImportStatePersist: true,
+				// only used when the server is created, so they can not be imported
+				ImportStateVerifyIgnore: []string{
+					"login",
+					"host",
+					"avoid_host",
+					"default_user_password",
+				},
+			},
+			// the first plan after import is clean
+			{
+				Config:   testAccUpcloudServerImportConfig(os.Getenv(testAccNetworkEnv)),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+// testAccUpcloudServerImportConfig leaves out user_data, zone_type, delete_backups and timeouts:
+// they can not be read back from the API, so configuring them shows an in-place update after import.
+func testAccUpcloudServerImportConfig(network string) string {
+	return fmt.Sprintf(`
+resource "upcloud_server" "test" {
+  hostname    = "tf-acc-import"
+  zone        = "fi-hel1"
+  nic_model   = "e1000"
+  video_model = "cirrus"
+  timezone    = "Europe/Helsinki"
+
+  network_interface {
+    type = "public"
+  }
+
+  network_interface {
+    type    = "private"
+    network = %[1]q
+  }
+
+  storage_devices {
+    size = 10
+  }
+
+  storage_devices {
+    size = 20
+    tier = "standard"
+  }
+}
+`, network)
+}
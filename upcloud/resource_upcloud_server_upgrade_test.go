@@ -0,0 +1,92 @@
+package upcloud
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerResourceUpgradeState(t *testing.T) {
+	testCases := []struct {
+		name       string
+		fixture    string
+		version    int64
+		hostname   string
+		zone       string
+		interfaces []string
+	}{
+		{
+			name:       "v0.1.0 public interface",
+			fixture:    "server_state_v0_public.json",
+			version:    0,
+			hostname:   "web-1",
+			zone:       "fi-hel1",
+			interfaces: []string{"94.237.0.10"},
+		},
+		{
+			name:       "v0.1.0 dual stack interfaces",
+			fixture:    "server_state_v0_dual_stack.json",
+			version:    0,
+			hostname:   "web-2",
+			zone:       "de-fra1",
+			interfaces: []string{"94.237.0.11", "2a04:3540:1000:310::1"},
+		},
+	}
+
+	ctx := context.Background()
+	server, err := providerserver.NewProtocol6WithError(New()())()
+	require.NoError(t, err)
+
+	schemaResp, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	require.NoError(t, err)
+	serverSchema := schemaResp.ResourceSchemas["upcloud_server"]
+	require.NotNil(t, serverSchema)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fixture, err := os.ReadFile(filepath.Join("testdata", testCase.fixture))
+			require.NoError(t, err)
+
+			resp, err := server.UpgradeResourceState(ctx, &tfprotov6.UpgradeResourceStateRequest{
+				TypeName: "upcloud_server",
+				Version:  testCase.version,
+				RawState: &tfprotov6.RawState{JSON: fixture},
+			})
+			require.NoError(t, err)
+			require.Empty(t, resp.Diagnostics)
+
+			upgraded, err := resp.UpgradedState.Unmarshal(serverSchema.ValueType())
+			require.NoError(t, err)
+			attrs := make(map[string]tftypes.Value)
+			require.NoError(t, upgraded.As(&attrs))
+
+			assert.Equal(t, testCase.hostname, stringValue(t, attrs["hostname"]))
+			assert.Equal(t, testCase.zone, stringValue(t, attrs["zone"]))
+			assert.Equal(t, "soft", stringValue(t, attrs["stop_type"]))
+
+			var interfaces []tftypes.Value
+			require.NoError(t, attrs["network_interface"].As(&interfaces))
+			addresses := make([]string, 0, len(interfaces))
+			for _, iface := range interfaces {
+				ifaceAttrs := make(map[string]tftypes.Value)
+				require.NoError(t, iface.As(&ifaceAttrs))
+				addresses = append(addresses, stringValue(t, ifaceAttrs["ip_address"]))
+			}
+			assert.Equal(t, testCase.interfaces, addresses)
+		})
+	}
+}
+
+func stringValue(t *testing.T, v tftypes.Value) string {
+	t.Helper()
+	var s string
+	require.NoError(t, v.As(&s))
+	return s
+}